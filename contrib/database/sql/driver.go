@@ -29,7 +29,8 @@ func (d *tracedDriver) Open(dsn string) (c driver.Conn, err error) {
 	)
 	meta, err = internal.ParseDSN(d.driverName, dsn)
 	if err != nil {
-		return nil, err
+		// the connection is still traced, without the tags of the DSN
+		d.config.tracer.Logger().Log(tracer.LevelWarn, "sqltrace: unable to parse the DSN", "driver", d.driverName, "error", err)
 	}
 	conn, err = d.Driver.Open(dsn)
 	if err != nil {
@@ -123,7 +123,7 @@ func (tc Conn) Do(commandName string, args ...interface{}) (reply interface{}, e
 		span.Finish()
 	}()
 
-	span.SetMeta("redis.args_length", strconv.Itoa(len(args)))
+	span.SetTag("redis.args_length", len(args))
 
 	if len(commandName) > 0 {
 		span.Resource = commandName
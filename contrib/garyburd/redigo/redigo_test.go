@@ -39,7 +39,7 @@ func TestClient(t *testing.T) {
 	assert.Equal(span.GetMeta("out.host"), "127.0.0.1")
 	assert.Equal(span.GetMeta("out.port"), "6379")
 	assert.Equal(span.GetMeta("redis.raw_command"), "SET 1 truck")
-	assert.Equal(span.GetMeta("redis.args_length"), "2")
+	assert.Equal(span.Metrics["redis.args_length"], float64(2))
 }
 
 func TestCommandError(t *testing.T) {
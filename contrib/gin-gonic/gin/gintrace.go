@@ -3,7 +3,6 @@ package gin
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/DataDog/dd-trace-go/tracer"
 	"github.com/DataDog/dd-trace-go/tracer/ext"
@@ -41,7 +40,7 @@ func Middleware(service string) gin.HandlerFunc {
 		// serve the request to the next middleware
 		c.Next()
 
-		span.SetMeta(ext.HTTPCode, strconv.Itoa(c.Writer.Status()))
+		span.SetTag(ext.HTTPCode, c.Writer.Status())
 
 		if len(c.Errors) > 0 {
 			span.SetMeta("gin.errors", c.Errors.String())
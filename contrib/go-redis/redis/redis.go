@@ -85,7 +85,7 @@ func (c *Pipeliner) ExecWithContext(ctx context.Context) ([]redis.Cmder, error)
 	}
 
 	span.Resource = commandsToString(cmds)
-	span.SetMeta("redis.pipeline_length", strconv.Itoa(len(cmds)))
+	span.SetTag("redis.pipeline_length", len(cmds))
 	span.Finish()
 
 	return cmds, err
@@ -105,7 +105,7 @@ func (c *Pipeliner) Exec() ([]redis.Cmder, error) {
 	}
 
 	span.Resource = commandsToString(cmds)
-	span.SetMeta("redis.pipeline_length", strconv.Itoa(len(cmds)))
+	span.SetTag("redis.pipeline_length", len(cmds))
 	span.Finish()
 
 	return cmds, err
@@ -143,7 +143,7 @@ func createWrapperFromClient(tc *Client) func(oldProcess func(cmd redis.Cmder) e
 			span.Service = p.config.serviceName
 			span.Resource = parts[0]
 			span.SetMeta("redis.raw_command", raw)
-			span.SetMeta("redis.args_length", strconv.Itoa(length))
+			span.SetTag("redis.args_length", length)
 			span.SetMeta("out.host", p.host)
 			span.SetMeta("out.port", p.port)
 			span.SetMeta("out.db", p.db)
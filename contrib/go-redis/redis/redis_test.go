@@ -34,7 +34,7 @@ func TestClient(t *testing.T) {
 	assert.Equal(span.GetMeta("out.host"), "127.0.0.1")
 	assert.Equal(span.GetMeta("out.port"), "6379")
 	assert.Equal(span.GetMeta("redis.raw_command"), "set test_key test_value: ")
-	assert.Equal(span.GetMeta("redis.args_length"), "3")
+	assert.Equal(span.Metrics["redis.args_length"], float64(3))
 }
 
 func TestPipeline(t *testing.T) {
@@ -60,7 +60,7 @@ func TestPipeline(t *testing.T) {
 	assert.Equal(span.Service, "my-redis")
 	assert.Equal(span.Name, "redis.command")
 	assert.Equal(span.GetMeta("out.port"), "6379")
-	assert.Equal(span.GetMeta("redis.pipeline_length"), "1")
+	assert.Equal(span.Metrics["redis.pipeline_length"], float64(1))
 	assert.Equal(span.Resource, "expire pipeline_counter 3600: false\n")
 
 	pipeline.Expire("pipeline_counter", time.Hour)
@@ -78,7 +78,7 @@ func TestPipeline(t *testing.T) {
 	span = spans[0]
 	assert.Equal(span.Service, "my-redis")
 	assert.Equal(span.Name, "redis.command")
-	assert.Equal(span.GetMeta("redis.pipeline_length"), "2")
+	assert.Equal(span.Metrics["redis.pipeline_length"], float64(2))
 	assert.Equal(span.Resource, "expire pipeline_counter 3600: false\nexpire pipeline_counter_1 60: false\n")
 }
 
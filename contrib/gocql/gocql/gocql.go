@@ -3,7 +3,6 @@ package gocql
 
 import (
 	"context"
-	"fmt"
 	"strconv"
 	"strings"
 
@@ -46,6 +45,7 @@ func WrapQuery(q *gocql.Query, opts ...WrapOption) *Query {
 	if err != nil {
 		// An invalid string, so that the trace is not dropped
 		// due to having an empty resource
+		cfg.tracer.Logger().Log(tracer.LevelDebug, "gocqltrace: unable to unquote the query", "error", err)
 		query = "_"
 	}
 	tq := &Query{q, &params{
@@ -77,7 +77,7 @@ func (tq *Query) newChildSpan(ctx context.Context) *tracer.Span {
 	span.Type = ext.CassandraType
 	span.Service = p.config.serviceName
 	span.Resource = p.query
-	span.SetMeta(ext.CassandraPaginated, fmt.Sprintf("%t", p.paginated))
+	span.SetTag(ext.CassandraPaginated, p.paginated)
 	span.SetMeta(ext.CassandraKeyspace, p.keyspace)
 	return span
 }
@@ -124,7 +124,7 @@ func (tq *Query) ScanCAS(dest ...interface{}) (applied bool, err error) {
 func (tq *Query) Iter() *Iter {
 	iter := tq.Query.Iter()
 	span := tq.newChildSpan(tq.traceContext)
-	span.SetMeta(ext.CassandraRowCount, strconv.Itoa(iter.NumRows()))
+	span.SetTag(ext.CassandraRowCount, iter.NumRows())
 	span.SetMeta(ext.CassandraConsistencyLevel, strconv.Itoa(int(tq.GetConsistency())))
 
 	columns := iter.Columns()
@@ -4,6 +4,7 @@ package grpc
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/DataDog/dd-trace-go/tracer"
 	"github.com/DataDog/dd-trace-go/tracer/ext"
@@ -15,8 +16,11 @@ import (
 
 // pass trace ids with these headers
 const (
-	traceIDKey  = "x-datadog-trace-id"
-	parentIDKey = "x-datadog-parent-id"
+	traceIDKey          = "x-datadog-trace-id"
+	parentIDKey         = "x-datadog-parent-id"
+	samplingPriorityKey = "x-datadog-sampling-priority"
+	originKey           = "x-datadog-origin"
+	baggageKeyPrefix    = "ot-baggage-"
 )
 
 // UnaryServerInterceptor will trace requests to the given grpc server.
@@ -79,16 +83,23 @@ func UnaryClientInterceptor(opts ...InterceptorOption) grpc.UnaryClientIntercept
 }
 
 func serverSpan(t *tracer.Tracer, ctx context.Context, method, service string) *tracer.Span {
-	span := t.NewRootSpan("grpc.server", service, method)
+	var span *tracer.Span
+	if traceID, parentID := getIDs(ctx); traceID != 0 && parentID != 0 {
+		// continue the trace propagated by the client
+		parent := tracer.RemoteParent{
+			TraceID: traceID,
+			SpanID:  parentID,
+			Origin:  getOrigin(ctx),
+			Baggage: getBaggage(ctx),
+		}
+		parent.Priority, parent.HasPriority = getSamplingPriority(ctx)
+		span = t.NewRemoteChildSpan("grpc.server", service, method, parent)
+	} else {
+		span = t.NewRootSpan("grpc.server", service, method)
+	}
 	span.SetMeta("gprc.method", method)
 	span.Type = "go"
 
-	traceID, parentID := getIDs(ctx)
-	if traceID != 0 && parentID != 0 {
-		span.TraceID = traceID
-		span.ParentID = parentID
-	}
-
 	return span
 }
 
@@ -101,6 +112,18 @@ func setIDs(span *tracer.Span, ctx context.Context) context.Context {
 		traceIDKey:  fmt.Sprint(span.TraceID),
 		parentIDKey: fmt.Sprint(span.ParentID),
 	})
+	span.RLock()
+	if span.HasSamplingPriority() {
+		md[samplingPriorityKey] = []string{strconv.Itoa(span.GetSamplingPriority())}
+	}
+	span.RUnlock()
+	if origin := span.GetOrigin(); origin != "" {
+		md[originKey] = []string{origin}
+	}
+	span.ForeachBaggageItem(func(key, value string) bool {
+		md[baggageKeyPrefix+key] = []string{value}
+		return true
+	})
 	if existing, ok := metadata.FromContext(ctx); ok {
 		md = metadata.Join(existing, md)
 	}
@@ -120,6 +143,45 @@ func getIDs(ctx context.Context) (traceID, parentID uint64) {
 	return traceID, parentID
 }
 
+// getSamplingPriority returns the sampling priority embedded in the context,
+// if any.
+func getSamplingPriority(ctx context.Context) (int, bool) {
+	if md, ok := metadata.FromContext(ctx); ok {
+		for _, str := range md[samplingPriorityKey] {
+			if priority, err := strconv.Atoi(str); err == nil {
+				return priority, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// getOrigin returns the trace origin embedded in the context, if any.
+func getOrigin(ctx context.Context) string {
+	if md, ok := metadata.FromContext(ctx); ok {
+		if origin := md[originKey]; len(origin) > 0 {
+			return origin[0]
+		}
+	}
+	return ""
+}
+
+// getBaggage returns the baggage items embedded in the context, if any.
+func getBaggage(ctx context.Context) map[string]string {
+	var baggage map[string]string
+	if md, ok := metadata.FromContext(ctx); ok {
+		for key, values := range md {
+			if strings.HasPrefix(key, baggageKeyPrefix) && len(values) > 0 {
+				if baggage == nil {
+					baggage = make(map[string]string)
+				}
+				baggage[key[len(baggageKeyPrefix):]] = values[0]
+			}
+		}
+	}
+	return baggage
+}
+
 // getID parses an id from the metadata.
 func getID(md metadata.MD, name string) uint64 {
 	for _, str := range md[name] {
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	context "golang.org/x/net/context"
 
@@ -178,6 +179,35 @@ func TestPass(t *testing.T) {
 	assert.True(s.Duration > 0)
 }
 
+func TestPropagation(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, _ := tracertest.GetTestTracer()
+	testTracer.SetDebugLogging(debug)
+	testTracer.SetSampleRate(0)
+
+	span := testTracer.NewRootSpan("a", "b", "c")
+	span.SetSamplingPriority(2)
+	span.SetOrigin("synthetics")
+	span.SetBaggageItem("usr.id", "42")
+	ctx := setIDs(span, context.Background())
+
+	md, ok := metadata.FromContext(ctx)
+	assert.True(ok)
+	assert.Equal([]string{"2"}, md["x-datadog-sampling-priority"])
+	assert.Equal([]string{"synthetics"}, md["x-datadog-origin"])
+	assert.Equal([]string{"42"}, md["ot-baggage-usr.id"])
+
+	// the server span inherits the sampling decision made by the client
+	ctx = metadata.NewContext(context.Background(), md)
+	server := serverSpan(testTracer, ctx, "/grpc.Fixture/Ping", "grpc")
+	assert.Equal(span.TraceID, server.TraceID)
+	assert.True(server.HasSamplingPriority())
+	assert.Equal(2, server.GetSamplingPriority())
+	assert.Equal("synthetics", server.GetOrigin())
+	assert.Equal("42", server.BaggageItem("usr.id"))
+	assert.True(server.Sampled)
+}
+
 // fixtureServer a dummy implemenation of our grpc fixtureServer.
 type fixtureServer struct{}
 
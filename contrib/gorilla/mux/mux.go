@@ -7,6 +7,7 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/DataDog/dd-trace-go/contrib/internal"
+	"github.com/DataDog/dd-trace-go/tracer"
 	"github.com/DataDog/dd-trace-go/tracer/ext"
 )
 
@@ -44,6 +45,7 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	if r.Match(req, &match) {
 		route, err = match.Route.GetPathTemplate()
 		if err != nil {
+			r.config.tracer.Logger().Log(tracer.LevelDebug, "muxtrace: unable to get the path template of the route", "error", err)
 			route = "unknown"
 		}
 	} else {
@@ -3,11 +3,21 @@ package internal
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/DataDog/dd-trace-go/tracer"
 	"github.com/DataDog/dd-trace-go/tracer/ext"
 )
 
+// pass trace ids, sampling priority, origin and baggage with these headers
+const (
+	traceIDHeader          = "x-datadog-trace-id"
+	parentIDHeader         = "x-datadog-parent-id"
+	samplingPriorityHeader = "x-datadog-sampling-priority"
+	originHeader           = "x-datadog-origin"
+	baggageHeaderPrefix    = "ot-baggage-"
+)
+
 // TraceAndServe will apply tracing to the given http.Handler using the passed tracer under the given service and resource.
 func TraceAndServe(h http.Handler, w http.ResponseWriter, r *http.Request, service, resource string, t *tracer.Tracer) {
 	// bail out if tracing isn't enabled
@@ -16,7 +26,16 @@ func TraceAndServe(h http.Handler, w http.ResponseWriter, r *http.Request, servi
 		return
 	}
 
-	span, ctx := t.NewChildSpanWithContext("http.request", r.Context())
+	var span *tracer.Span
+	if _, ok := tracer.SpanFromContext(r.Context()); ok {
+		span = t.NewChildSpanFromContext("http.request", r.Context())
+	} else if parent, ok := extractHeaders(r.Header); ok {
+		// no local parent, continue the trace propagated by the client
+		span = t.NewRemoteChildSpan("http.request", service, resource, parent)
+	} else {
+		span = t.NewChildSpanFromContext("http.request", r.Context())
+	}
+	ctx := span.Context(r.Context())
 	defer span.Finish()
 
 	span.Type = ext.HTTPType
@@ -31,6 +50,37 @@ func TraceAndServe(h http.Handler, w http.ResponseWriter, r *http.Request, servi
 	h.ServeHTTP(traceWriter, traceRequest)
 }
 
+// extractHeaders returns the trace context propagated in the given HTTP
+// headers: trace and parent IDs, sampling priority, origin and baggage
+// items. It returns false if no valid trace context has been propagated.
+func extractHeaders(h http.Header) (tracer.RemoteParent, bool) {
+	var parent tracer.RemoteParent
+	traceID, err := strconv.ParseUint(h.Get(traceIDHeader), 10, 64)
+	if err != nil || traceID == 0 {
+		return parent, false
+	}
+	parentID, err := strconv.ParseUint(h.Get(parentIDHeader), 10, 64)
+	if err != nil || parentID == 0 {
+		return parent, false
+	}
+	parent.TraceID = traceID
+	parent.SpanID = parentID
+	if priority, err := strconv.Atoi(h.Get(samplingPriorityHeader)); err == nil {
+		parent.Priority = priority
+		parent.HasPriority = true
+	}
+	parent.Origin = h.Get(originHeader)
+	for k := range h {
+		if key := strings.ToLower(k); strings.HasPrefix(key, baggageHeaderPrefix) {
+			if parent.Baggage == nil {
+				parent.Baggage = make(map[string]string)
+			}
+			parent.Baggage[key[len(baggageHeaderPrefix):]] = h.Get(k)
+		}
+	}
+	return parent, true
+}
+
 // ResponseWriter is a small wrapper around an http response writer that will
 // intercept and store the status of a request.
 // It implements the ResponseWriter interface.
@@ -60,7 +110,7 @@ func (w *ResponseWriter) Write(b []byte) (int, error) {
 func (w *ResponseWriter) WriteHeader(status int) {
 	w.ResponseWriter.WriteHeader(status)
 	w.status = status
-	w.span.SetMeta(ext.HTTPCode, strconv.Itoa(status))
+	w.span.SetTag(ext.HTTPCode, status)
 	if status >= 500 && status < 600 {
 		w.span.Error = 1
 	}
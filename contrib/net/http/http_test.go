@@ -128,6 +128,53 @@ func TestWrapHandler200(t *testing.T) {
 	assert.Equal(int32(0), s.Error)
 }
 
+func TestHttpTracerPropagation(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport, router := setup(t)
+	tracer.SetSampleRate(0)
+
+	r := httptest.NewRequest("GET", "/200", nil)
+	r.Header.Set("x-datadog-trace-id", "1234")
+	r.Header.Set("x-datadog-parent-id", "5678")
+	r.Header.Set("x-datadog-sampling-priority", "2")
+	r.Header.Set("x-datadog-origin", "synthetics")
+	r.Header.Set("ot-baggage-usr.id", "42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(200, w.Code)
+
+	// the sampling decision is inherited, even if the local sampler rejects everything
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Equal(1, len(traces))
+	spans := traces[0]
+	assert.Equal(1, len(spans))
+
+	s := spans[0]
+	assert.Equal(uint64(1234), s.TraceID)
+	assert.Equal(uint64(5678), s.ParentID)
+	assert.True(s.HasSamplingPriority())
+	assert.Equal(2, s.GetSamplingPriority())
+	assert.Equal("synthetics", s.GetOrigin())
+	assert.Equal("42", s.BaggageItem("usr.id"))
+}
+
+func TestHttpTracerPropagationReject(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport, router := setup(t)
+
+	r := httptest.NewRequest("GET", "/200", nil)
+	r.Header.Set("x-datadog-trace-id", "1234")
+	r.Header.Set("x-datadog-parent-id", "5678")
+	r.Header.Set("x-datadog-sampling-priority", "-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(200, w.Code)
+
+	tracer.ForceFlush()
+	assert.Equal(0, len(transport.Traces()))
+}
+
 func setup(t *testing.T) (*tracer.Tracer, *tracertest.DummyTransport, http.Handler) {
 	h200 := handler200(t)
 	h500 := handler500(t)
@@ -69,7 +69,7 @@ func (t *httpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 		res.Body = ioutil.NopCloser(bytes.NewBuffer(buf))
 	}
 	if res != nil {
-		span.SetMeta(ext.HTTPCode, strconv.Itoa(res.StatusCode))
+		span.SetTag(ext.HTTPCode, res.StatusCode)
 	}
 
 	quantize(span)
@@ -19,6 +19,10 @@ type Configuration struct {
 
 	// SampleRate sets the Tracer sample rate (ext/priority.go).
 	SampleRate float64
+	// ComputeStats, when true, computes trace stats (hits, errors and
+	// latency distributions) in the tracer, before sampling. It requires
+	// an agent supporting the v0.6/stats endpoint.
+	ComputeStats bool
 
 	// AgentHostname specifies the hostname of the agent where the traces
 	// are sent to.
@@ -33,6 +37,9 @@ type Configuration struct {
 
 	// TextMapPropagator is an injector used for Context propagation.
 	TextMapPropagator Propagator
+	// BinaryPropagator is an injector used for Context propagation
+	// through binary carriers (io.Writer and io.Reader).
+	BinaryPropagator Propagator
 }
 
 // NewConfiguration creates a `Configuration` object with default values.
@@ -50,6 +57,7 @@ func NewConfiguration() *Configuration {
 		AgentPort:         "8126",
 		GlobalTags:        make(map[string]interface{}),
 		TextMapPropagator: NewTextMapPropagator("", "", ""),
+		BinaryPropagator:  NewBinaryPropagator(),
 	}
 }
 
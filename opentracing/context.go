@@ -9,18 +9,50 @@ type SpanContext struct {
 	sampled  bool
 	span     *Span
 	baggage  map[string]string
+
+	// priority holds the sampling priority received from another process,
+	// it is only relevant when hasPriority is true.
+	priority    int
+	hasPriority bool
+	// origin holds the trace origin received from another process.
+	origin string
 }
 
 // ForeachBaggageItem grants access to all baggage items stored in the
 // SpanContext
 func (c SpanContext) ForeachBaggageItem(handler func(k, v string) bool) {
-	for k, v := range c.baggage {
+	for k, v := range c.baggageItems() {
 		if !handler(k, v) {
 			break
 		}
 	}
 }
 
+// baggageItems returns the baggage items of the SpanContext. The baggage of
+// a local Span is held by the Datadog Span, so that the items set with the
+// tracer package, e.g. by tracer.SetUser, are propagated too; the items set
+// on the SpanContext itself with WithBaggageItem take precedence.
+func (c SpanContext) baggageItems() map[string]string {
+	if c.span == nil || c.span.Span == nil {
+		return c.baggage
+	}
+	var items map[string]string
+	c.span.Span.ForeachBaggageItem(func(k, v string) bool {
+		if items == nil {
+			items = make(map[string]string)
+		}
+		items[k] = v
+		return true
+	})
+	if items == nil {
+		return c.baggage
+	}
+	for k, v := range c.baggage {
+		items[k] = v
+	}
+	return items
+}
+
 // WithBaggageItem returns an entirely new SpanContext with the
 // given key:value baggage pair set.
 func (c SpanContext) WithBaggageItem(key, val string) SpanContext {
@@ -42,5 +74,32 @@ func (c SpanContext) WithBaggageItem(key, val string) SpanContext {
 		sampled:  c.sampled,
 		span:     c.span,
 		baggage:  newBaggage,
+
+		priority:    c.priority,
+		hasPriority: c.hasPriority,
+		origin:      c.origin,
+	}
+}
+
+// samplingPriority returns the sampling priority of the SpanContext and
+// whether one is set. The priority of a local Span always takes precedence
+// over the propagated one, since it can be changed after the Span creation.
+func (c SpanContext) samplingPriority() (int, bool) {
+	if c.span != nil && c.span.Span != nil {
+		c.span.Span.RLock()
+		defer c.span.Span.RUnlock()
+		if c.span.Span.HasSamplingPriority() {
+			return c.span.Span.GetSamplingPriority(), true
+		}
+	}
+	return c.priority, c.hasPriority
+}
+
+// traceOrigin returns the origin of the trace the SpanContext belongs to,
+// or the empty string if there is none.
+func (c SpanContext) traceOrigin() string {
+	if c.span != nil && c.span.Span != nil {
+		return c.span.Span.GetOrigin()
 	}
+	return c.origin
 }
@@ -0,0 +1,50 @@
+package opentracing
+
+import (
+	"fmt"
+
+	ot "github.com/opentracing/opentracing-go"
+)
+
+const (
+	// referenceKey is the tag describing how a Span relates to its parent,
+	// it is only set when the parent is not a ChildOf reference.
+	referenceKey = "span.reference"
+	// referenceAttr is the link attribute holding the OpenTracing reference type.
+	referenceAttr = "reference"
+)
+
+// AddSpanLink links the Span identified by ctx, usually extracted from a
+// carrier, to the given Span. It is meant for spans related to several
+// traces, such as a consumer processing a batch of messages. It returns
+// false if span or ctx have not been created by this package.
+//
+//	span := tracer.StartSpan("kafka.consume")
+//	for _, msg := range batch {
+//		if ctx, err := tracer.Extract(opentracing.TextMap, carrier(msg)); err == nil {
+//			AddSpanLink(span, ctx, nil)
+//		}
+//	}
+func AddSpanLink(span ot.Span, ctx ot.SpanContext, attrs map[string]string) bool {
+	s, ok := span.(*Span)
+	if !ok {
+		return false
+	}
+	c, ok := ctx.(SpanContext)
+	if !ok {
+		return false
+	}
+	s.Span.AddLink(c.traceID, c.spanID, attrs)
+	return true
+}
+
+// referenceName returns the name of an OpenTracing reference type.
+func referenceName(refType ot.SpanReferenceType) string {
+	switch refType {
+	case ot.ChildOfRef:
+		return "child_of"
+	case ot.FollowsFromRef:
+		return "follows_from"
+	}
+	return fmt.Sprint(refType)
+}
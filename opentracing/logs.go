@@ -0,0 +1,133 @@
+package opentracing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+const (
+	// maxLogValueLen is the maximum length of a string value in a log record,
+	// longer values are truncated.
+	maxLogValueLen = 1024
+
+	// defaultEventName is the name of a log record that has no "event" field.
+	defaultEventName = "log"
+)
+
+// logRecord records the given fields as a span event. Fields following the
+// OpenTracing semantic conventions for errors (event=error, or an
+// error.object holding an error whatever the event) also mark the Span as
+// errored.
+func (s *Span) logRecord(t time.Time, fields []log.Field) {
+	if len(fields) == 0 {
+		return
+	}
+	enc := make(fieldEncoder, len(fields))
+	for _, f := range fields {
+		f.Marshal(enc)
+	}
+
+	name := defaultEventName
+	if v, ok := enc["event"].(string); ok {
+		name = v
+		delete(enc, "event")
+	}
+	if name == "error" || hasErrorObject(fields) {
+		s.logError(fields)
+	}
+	s.Span.AddEventWithTime(name, t.UnixNano(), enc)
+}
+
+// hasErrorObject returns true if the fields have an error.object field
+// holding an error, as log.Error sets.
+func hasErrorObject(fields []log.Field) bool {
+	for _, f := range fields {
+		if f.Key() == "error.object" {
+			if _, ok := f.Value().(error); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// logError sets the error of the Span from the fields of an error log record.
+func (s *Span) logError(fields []log.Field) {
+	var (
+		err                  error
+		message, kind, stack string
+	)
+	for _, f := range fields {
+		switch f.Key() {
+		case "error.object":
+			if e, ok := f.Value().(error); ok {
+				err = e
+			}
+		case "message":
+			message = fmt.Sprint(f.Value())
+		case "error.kind":
+			kind = fmt.Sprint(f.Value())
+		case "stack":
+			stack = fmt.Sprint(f.Value())
+		}
+	}
+	if err == nil {
+		if message == "" {
+			message = "error"
+		}
+		err = errors.New(message)
+	}
+	s.Span.SetError(err)
+	// explicit values from the log record take precedence over the computed ones
+	if kind != "" {
+		s.Span.SetMeta(ext.ErrorType, kind)
+	}
+	if stack != "" {
+		s.Span.SetMeta(ext.ErrorStack, stack)
+	}
+}
+
+// fieldEncoder implements log.Encoder, converting log fields into values
+// that can safely be JSON encoded.
+type fieldEncoder map[string]interface{}
+
+func (e fieldEncoder) EmitString(key, value string) {
+	if len(value) > maxLogValueLen {
+		value = value[:maxLogValueLen] + "..."
+	}
+	e[key] = value
+}
+
+func (e fieldEncoder) EmitBool(key string, value bool)       { e[key] = value }
+func (e fieldEncoder) EmitInt(key string, value int)         { e[key] = value }
+func (e fieldEncoder) EmitInt32(key string, value int32)     { e[key] = value }
+func (e fieldEncoder) EmitInt64(key string, value int64)     { e[key] = value }
+func (e fieldEncoder) EmitUint32(key string, value uint32)   { e[key] = value }
+func (e fieldEncoder) EmitUint64(key string, value uint64)   { e[key] = value }
+func (e fieldEncoder) EmitFloat32(key string, value float32) { e.EmitFloat64(key, float64(value)) }
+
+func (e fieldEncoder) EmitFloat64(key string, value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		// not representable in JSON
+		e[key] = fmt.Sprint(value)
+		return
+	}
+	e[key] = value
+}
+
+func (e fieldEncoder) EmitObject(key string, value interface{}) {
+	if err, ok := value.(error); ok {
+		e.EmitString(key, err.Error())
+		return
+	}
+	e.EmitString(key, fmt.Sprint(value))
+}
+
+func (e fieldEncoder) EmitLazyLogger(value log.LazyLogger) {
+	value(e)
+}
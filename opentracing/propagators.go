@@ -1,6 +1,9 @@
 package opentracing
 
 import (
+	"bytes"
+	"encoding/binary"
+	"io"
 	"strconv"
 	"strings"
 
@@ -23,6 +26,8 @@ const (
 	defaultBaggageHeaderPrefix = "ot-baggage-"
 	defaultTraceIDHeader       = "x-datadog-trace-id"
 	defaultParentIDHeader      = "x-datadog-parent-id"
+	samplingPriorityHeader     = "x-datadog-sampling-priority"
+	originHeader               = "x-datadog-origin"
 )
 
 // NewTextMapPropagator returns a new propagator which uses opentracing.TextMap
@@ -54,7 +59,8 @@ type TextMapPropagator struct {
 
 // Inject defines the TextMapPropagator to propagate SpanContext data
 // out of the current process. The implementation propagates the
-// TraceID and the current active SpanID, as well as the Span baggage.
+// TraceID and the current active SpanID, the sampling priority and the
+// trace origin, as well as the Span baggage.
 func (p *TextMapPropagator) Inject(context ot.SpanContext, carrier interface{}) error {
 	ctx, ok := context.(SpanContext)
 	if !ok {
@@ -68,9 +74,15 @@ func (p *TextMapPropagator) Inject(context ot.SpanContext, carrier interface{})
 	// propagate the TraceID and the current active SpanID
 	writer.Set(p.traceHeader, strconv.FormatUint(ctx.traceID, 10))
 	writer.Set(p.parentHeader, strconv.FormatUint(ctx.spanID, 10))
+	if priority, ok := ctx.samplingPriority(); ok {
+		writer.Set(samplingPriorityHeader, strconv.Itoa(priority))
+	}
+	if origin := ctx.traceOrigin(); origin != "" {
+		writer.Set(originHeader, origin)
+	}
 
 	// propagate OpenTracing baggage
-	for k, v := range ctx.baggage {
+	for k, v := range ctx.baggageItems() {
 		writer.Set(p.baggagePrefix+k, v)
 	}
 	return nil
@@ -84,6 +96,9 @@ func (p *TextMapPropagator) Extract(carrier interface{}) (ot.SpanContext, error)
 	}
 	var err error
 	var traceID, parentID uint64
+	var priority int
+	var hasPriority bool
+	var origin string
 	decodedBaggage := make(map[string]string)
 
 	// extract SpanContext fields
@@ -99,6 +114,14 @@ func (p *TextMapPropagator) Extract(carrier interface{}) (ot.SpanContext, error)
 			if err != nil {
 				return ot.ErrSpanContextCorrupted
 			}
+		case samplingPriorityHeader:
+			priority, err = strconv.Atoi(v)
+			if err != nil {
+				return ot.ErrSpanContextCorrupted
+			}
+			hasPriority = true
+		case originHeader:
+			origin = v
 		default:
 			lowercaseK := strings.ToLower(k)
 			if strings.HasPrefix(lowercaseK, p.baggagePrefix) {
@@ -118,8 +141,222 @@ func (p *TextMapPropagator) Extract(carrier interface{}) (ot.SpanContext, error)
 	}
 
 	return SpanContext{
-		traceID: traceID,
-		spanID:  parentID,
-		baggage: decodedBaggage,
+		traceID:     traceID,
+		spanID:      parentID,
+		baggage:     decodedBaggage,
+		priority:    priority,
+		hasPriority: hasPriority,
+		origin:      origin,
 	}, nil
 }
+
+const (
+	// binaryVersion is the version of the binary encoding written by the
+	// BinaryPropagator. It's the first byte of every payload and it must be
+	// increased whenever the format changes.
+	binaryVersion byte = 1
+	// binaryMaxPayloadSize is the maximum size of a binary payload we accept
+	// to decode; anything above is considered as corrupted.
+	binaryMaxPayloadSize = 1 << 20
+	// binaryFlagPriority is set in the flags byte when the payload contains
+	// a sampling priority.
+	binaryFlagPriority byte = 1 << 0
+	// binaryFlagOrigin is set in the flags byte when the payload contains
+	// a trace origin.
+	binaryFlagOrigin byte = 1 << 1
+	// binaryKnownFlags holds all the flags this version is able to read.
+	binaryKnownFlags = binaryFlagPriority | binaryFlagOrigin
+)
+
+// NewBinaryPropagator returns a new propagator which uses opentracing.Binary
+// to inject and extract values. Inject expects an io.Writer carrier and
+// Extract expects an io.Reader carrier.
+func NewBinaryPropagator() *BinaryPropagator {
+	return &BinaryPropagator{}
+}
+
+// BinaryPropagator implements a propagator which uses a compact and versioned
+// binary encoding. A payload is made of a version byte, followed by the
+// payload length (uint32, big endian) and by the payload itself:
+//
+//	trace ID    uint64, big endian
+//	span ID     uint64, big endian
+//	flags       byte
+//	priority    varint, only if the priority flag is set
+//	origin      uvarint-prefixed string, only if the origin flag is set
+//	baggage     uvarint count, followed by uvarint-prefixed keys and values
+//
+// The length prefix allows the carrier to be a stream that is shared with
+// other data, since Extract never reads past the end of the payload.
+type BinaryPropagator struct{}
+
+// Inject defines the BinaryPropagator to propagate SpanContext data
+// out of the current process. The implementation propagates the
+// TraceID, the current active SpanID, the sampling priority, the
+// trace origin and the Span baggage.
+func (p *BinaryPropagator) Inject(context ot.SpanContext, carrier interface{}) error {
+	ctx, ok := context.(SpanContext)
+	if !ok {
+		return ot.ErrInvalidSpanContext
+	}
+	writer, ok := carrier.(io.Writer)
+	if !ok {
+		return ot.ErrInvalidCarrier
+	}
+
+	var (
+		payload bytes.Buffer
+		scratch [binary.MaxVarintLen64]byte
+		flags   byte
+	)
+	putUvarint := func(v uint64) {
+		payload.Write(scratch[:binary.PutUvarint(scratch[:], v)])
+	}
+	putString := func(v string) {
+		putUvarint(uint64(len(v)))
+		payload.WriteString(v)
+	}
+
+	binary.BigEndian.PutUint64(scratch[:8], ctx.traceID)
+	payload.Write(scratch[:8])
+	binary.BigEndian.PutUint64(scratch[:8], ctx.spanID)
+	payload.Write(scratch[:8])
+
+	priority, hasPriority := ctx.samplingPriority()
+	if hasPriority {
+		flags |= binaryFlagPriority
+	}
+	origin := ctx.traceOrigin()
+	if origin != "" {
+		flags |= binaryFlagOrigin
+	}
+	payload.WriteByte(flags)
+	if hasPriority {
+		payload.Write(scratch[:binary.PutVarint(scratch[:], int64(priority))])
+	}
+	if origin != "" {
+		putString(origin)
+	}
+
+	baggage := ctx.baggageItems()
+	putUvarint(uint64(len(baggage)))
+	for k, v := range baggage {
+		putString(k)
+		putString(v)
+	}
+
+	header := make([]byte, 5, 5+payload.Len())
+	header[0] = binaryVersion
+	binary.BigEndian.PutUint32(header[1:], uint32(payload.Len()))
+	_, err := writer.Write(append(header, payload.Bytes()...))
+	return err
+}
+
+// Extract implements Propagator.
+func (p *BinaryPropagator) Extract(carrier interface{}) (ot.SpanContext, error) {
+	reader, ok := carrier.(io.Reader)
+	if !ok {
+		return nil, ot.ErrInvalidCarrier
+	}
+
+	var header [5]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		if err == io.EOF {
+			// nothing has been propagated
+			return nil, ot.ErrSpanContextNotFound
+		}
+		return nil, ot.ErrSpanContextCorrupted
+	}
+	if header[0] != binaryVersion {
+		return nil, ot.ErrSpanContextCorrupted
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > binaryMaxPayloadSize {
+		return nil, ot.ErrSpanContextCorrupted
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, ot.ErrSpanContextCorrupted
+	}
+
+	ctx, err := decodeBinaryPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.traceID == 0 || ctx.spanID == 0 {
+		return nil, ot.ErrSpanContextNotFound
+	}
+	return ctx, nil
+}
+
+// decodeBinaryPayload decodes the payload written by BinaryPropagator.Inject,
+// the whole payload must be consumed for the SpanContext to be valid.
+func decodeBinaryPayload(payload []byte) (SpanContext, error) {
+	var ctx SpanContext
+	r := bytes.NewReader(payload)
+
+	readString := func() (string, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(r.Len()) {
+			return "", ot.ErrSpanContextCorrupted
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", ot.ErrSpanContextCorrupted
+		}
+		return string(buf), nil
+	}
+
+	var ids [17]byte
+	if _, err := io.ReadFull(r, ids[:]); err != nil {
+		return ctx, ot.ErrSpanContextCorrupted
+	}
+	ctx.traceID = binary.BigEndian.Uint64(ids[0:8])
+	ctx.spanID = binary.BigEndian.Uint64(ids[8:16])
+	flags := ids[16]
+	if flags&^binaryKnownFlags != 0 {
+		// unknown flags, the payload is not one we know how to read
+		return ctx, ot.ErrSpanContextCorrupted
+	}
+
+	if flags&binaryFlagPriority != 0 {
+		priority, err := binary.ReadVarint(r)
+		if err != nil {
+			return ctx, ot.ErrSpanContextCorrupted
+		}
+		ctx.priority = int(priority)
+		ctx.hasPriority = true
+	}
+	if flags&binaryFlagOrigin != 0 {
+		origin, err := readString()
+		if err != nil {
+			return ctx, err
+		}
+		ctx.origin = origin
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count > uint64(r.Len()) {
+		return ctx, ot.ErrSpanContextCorrupted
+	}
+	if count > 0 {
+		ctx.baggage = make(map[string]string, count)
+	}
+	for i := uint64(0); i < count; i++ {
+		k, err := readString()
+		if err != nil {
+			return ctx, err
+		}
+		v, err := readString()
+		if err != nil {
+			return ctx, err
+		}
+		ctx.baggage[k] = v
+	}
+
+	if r.Len() != 0 {
+		// trailing bytes in the payload
+		return ctx, ot.ErrSpanContextCorrupted
+	}
+	return ctx, nil
+}
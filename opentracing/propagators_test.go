@@ -1,10 +1,16 @@
 package opentracing
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
 	"net/http"
 	"strconv"
 	"testing"
 
+	ddtrace "github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
 )
@@ -79,3 +85,172 @@ func TestTracerTextMapPropagationHeader(t *testing.T) {
 	assert.Equal(headers.Get("pid"), pid)
 	assert.Equal(headers.Get("bg-item"), "x")
 }
+
+func TestTracerPropagationPriorityAndOrigin(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+	root := tracer.StartSpan("web.request").(*Span)
+	root.SetSamplingPriority(-1)
+	root.SetOrigin("synthetics")
+
+	headers := http.Header{}
+	carrier := opentracing.HTTPHeadersCarrier(headers)
+	err := tracer.Inject(root.Context(), opentracing.HTTPHeaders, carrier)
+	assert.Nil(err)
+	assert.Equal("-1", headers.Get("x-datadog-sampling-priority"))
+	assert.Equal("synthetics", headers.Get("x-datadog-origin"))
+
+	propagated, err := tracer.Extract(opentracing.HTTPHeaders, carrier)
+	assert.Nil(err)
+
+	// the downstream root span inherits the upstream decision
+	child := tracer.StartSpan("db.query", opentracing.ChildOf(propagated)).(*Span)
+	assert.True(child.Span.HasSamplingPriority())
+	assert.Equal(-1, child.Span.GetSamplingPriority())
+	assert.Equal("synthetics", child.Span.GetOrigin())
+	assert.False(child.Span.Sampled)
+	assert.False(child.Context().(SpanContext).sampled)
+
+	// the same goes for binary carriers
+	var buf bytes.Buffer
+	err = tracer.Inject(root.Context(), opentracing.Binary, &buf)
+	assert.Nil(err)
+	propagated, err = tracer.Extract(opentracing.Binary, &buf)
+	assert.Nil(err)
+	assert.Equal("synthetics", propagated.(SpanContext).origin)
+
+	// a corrupted priority is reported
+	headers.Set("x-datadog-sampling-priority", "high")
+	_, err = tracer.Extract(opentracing.HTTPHeaders, carrier)
+	assert.Equal(opentracing.ErrSpanContextCorrupted, err)
+}
+
+func TestTracerBinaryPropagation(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+	root := tracer.StartSpan("web.request").SetBaggageItem("item", "x").(*Span)
+	root.SetSamplingPriority(2)
+	ctx := root.Context()
+
+	var buf bytes.Buffer
+	err := tracer.Inject(ctx, opentracing.Binary, &buf)
+	assert.Nil(err)
+
+	propagated, err := tracer.Extract(opentracing.Binary, &buf)
+	assert.Nil(err)
+	tPropagated, ok := propagated.(SpanContext)
+	assert.True(ok)
+	assert.Equal(root.Span.TraceID, tPropagated.traceID)
+	assert.Equal(root.Span.SpanID, tPropagated.spanID)
+	assert.Equal(map[string]string{"item": "x"}, tPropagated.baggage)
+	priority, ok := tPropagated.samplingPriority()
+	assert.True(ok)
+	assert.Equal(2, priority)
+	assert.Equal(0, buf.Len())
+
+	// ensure a child can be created
+	child := tracer.StartSpan("db.query", opentracing.ChildOf(propagated)).(*Span)
+	assert.Equal(root.Span.TraceID, child.Span.TraceID)
+	assert.Equal(root.Span.SpanID, child.Span.ParentID)
+}
+
+func TestTracerPropagationNativeBaggage(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _, _ := NewTracer(NewConfiguration())
+
+	// the baggage set with the tracer package is propagated
+	root := tracer.StartSpan("web.request").(*Span)
+	ddtrace.SetUser(root.Span.Context(context.Background()), "42", ddtrace.WithUserPropagation())
+	root.SetBaggageItem("item", "x")
+	assert.Equal("42", root.BaggageItem(ext.UserID))
+	child := tracer.StartSpan("db.query", opentracing.ChildOf(root.Context())).(*Span)
+	assert.Equal("42", child.Span.BaggageItem(ext.UserID))
+	assert.Equal("x", child.Span.BaggageItem("item"))
+
+	headers := http.Header{}
+	err := tracer.Inject(child.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers))
+	assert.Nil(err)
+	assert.Equal("42", headers.Get("ot-baggage-usr.id"))
+	assert.Equal("x", headers.Get("ot-baggage-item"))
+
+	var buf bytes.Buffer
+	assert.Nil(tracer.Inject(child.Context(), opentracing.Binary, &buf))
+	propagated, err := tracer.Extract(opentracing.Binary, &buf)
+	assert.Nil(err)
+	assert.Equal(map[string]string{ext.UserID: "42", "item": "x"}, propagated.(SpanContext).baggage)
+
+	// and the propagated baggage is set on the Datadog Span
+	remote := tracer.StartSpan("web.request", opentracing.ChildOf(propagated)).(*Span)
+	assert.Equal("42", remote.Span.BaggageItem(ext.UserID))
+}
+
+func TestBinaryPropagatorRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	propagator := NewBinaryPropagator()
+
+	for _, ctx := range []SpanContext{
+		{traceID: 1, spanID: 2},
+		{traceID: math.MaxUint64, spanID: 42, priority: -1, hasPriority: true},
+		{traceID: 3, spanID: 4, baggage: map[string]string{"a": "b", "empty": "", "": "c"}},
+		{traceID: 5, spanID: 6, priority: 2, hasPriority: true, origin: "synthetics"},
+	} {
+		var buf bytes.Buffer
+		assert.Nil(propagator.Inject(ctx, &buf))
+		// extra data on the stream must not be consumed
+		buf.WriteString("trailing")
+
+		got, err := propagator.Extract(&buf)
+		assert.Nil(err)
+		assert.Equal(ctx, got)
+		assert.Equal("trailing", buf.String())
+	}
+}
+
+func TestBinaryPropagatorErrors(t *testing.T) {
+	assert := assert.New(t)
+	propagator := NewBinaryPropagator()
+
+	var valid bytes.Buffer
+	ctx := SpanContext{traceID: 1, spanID: 2, priority: 1, hasPriority: true, baggage: map[string]string{"key": "value"}}
+	assert.Nil(propagator.Inject(ctx, &valid))
+	payload := valid.Bytes()
+
+	// wrong carriers
+	assert.Equal(opentracing.ErrInvalidCarrier, propagator.Inject(ctx, "not a writer"))
+	_, err := propagator.Extract("not a reader")
+	assert.Equal(opentracing.ErrInvalidCarrier, err)
+	assert.Equal(opentracing.ErrInvalidSpanContext, propagator.Inject(opentracing.NoopTracer{}.StartSpan("x").Context(), &bytes.Buffer{}))
+
+	// nothing to extract
+	_, err = propagator.Extract(bytes.NewReader(nil))
+	assert.Equal(opentracing.ErrSpanContextNotFound, err)
+
+	var zero bytes.Buffer
+	assert.Nil(propagator.Inject(SpanContext{}, &zero))
+	_, err = propagator.Extract(&zero)
+	assert.Equal(opentracing.ErrSpanContextNotFound, err)
+
+	corrupt := func(f func(b []byte) []byte) []byte {
+		b := make([]byte, len(payload))
+		copy(b, payload)
+		return f(b)
+	}
+	for name, b := range map[string][]byte{
+		"version":          corrupt(func(b []byte) []byte { b[0] = 99; return b }),
+		"short-header":     payload[:3],
+		"truncated":        payload[:len(payload)-1],
+		"huge-size":        corrupt(func(b []byte) []byte { binary.BigEndian.PutUint32(b[1:], math.MaxUint32); return b }),
+		"short-size":       corrupt(func(b []byte) []byte { binary.BigEndian.PutUint32(b[1:], 10); return b }),
+		"unknown-flags":    corrupt(func(b []byte) []byte { b[5+16] = 0xff; return b }),
+		"trailing-payload": corrupt(func(b []byte) []byte { binary.BigEndian.PutUint32(b[1:], uint32(len(b)-4)); return append(b, 0) }),
+		"baggage-count":    corrupt(func(b []byte) []byte { b[5+18] = 100; return b }),
+		"baggage-length":   corrupt(func(b []byte) []byte { b[5+19] = 100; return b }),
+	} {
+		_, err := propagator.Extract(bytes.NewReader(b))
+		assert.Equal(opentracing.ErrSpanContextCorrupted, err, name)
+	}
+}
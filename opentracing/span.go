@@ -2,10 +2,13 @@ package opentracing
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	ddtrace "github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
 	ot "github.com/opentracing/opentracing-go"
+	otext "github.com/opentracing/opentracing-go/ext"
 	"github.com/opentracing/opentracing-go/log"
 )
 
@@ -30,40 +33,38 @@ func (s *Span) Context() ot.SpanContext {
 }
 
 // SetBaggageItem sets a key:value pair on this Span and its SpanContext
-// that also propagates to descendants of this Span.
+// that also propagates to descendants of this Span. The baggage is shared
+// with the Datadog Span, see tracer.Span.SetBaggageItem.
 func (s *Span) SetBaggageItem(key, val string) ot.Span {
-	s.Span.Lock()
-	defer s.Span.Unlock()
-
-	s.context = s.context.WithBaggageItem(key, val)
+	s.Span.SetBaggageItem(key, val)
 	return s
 }
 
 // BaggageItem gets the value for a baggage item given its key. Returns the empty string
 // if the value isn't found in this Span.
 func (s *Span) BaggageItem(key string) string {
-	s.Span.Lock()
-	defer s.Span.Unlock()
-
-	return s.context.baggage[key]
+	return s.context.baggageItems()[key]
 }
 
 // SetTag adds a tag to the span, overwriting pre-existing values for
-// the given `key`.
+// the given `key`. Tags are set with the SetTag method of the Datadog Span,
+// so numeric values are stored as metrics and the Datadog tags (service,
+// resource and span type) set the matching property of the Span, while the
+// OpenTracing standard tags (see github.com/opentracing/opentracing-go/ext)
+// are mapped to their Datadog counterpart:
+//
+//	error             flags the Span as failed
+//	http.status_code  stored as "http.status_code"; 5xx flags the Span as failed
+//	peer.hostname     stored as "out.host"
+//	peer.port         stored as "out.port"
+//	db.statement      used as the Span resource
+//	sampling.priority sets the sampling priority of the Span
+//	span.kind         stored as "span.kind"; server and consumer Spans are
+//	                  measured, and server Spans are typed "web" by default
+//
+// If the Span has been finished, it will not be modified by this method.
 func (s *Span) SetTag(key string, value interface{}) ot.Span {
 	switch key {
-	case ServiceName:
-		s.Span.Lock()
-		defer s.Span.Unlock()
-		s.Span.Service = fmt.Sprint(value)
-	case ResourceName:
-		s.Span.Lock()
-		defer s.Span.Unlock()
-		s.Span.Resource = fmt.Sprint(value)
-	case SpanType:
-		s.Span.Lock()
-		defer s.Span.Unlock()
-		s.Span.Type = fmt.Sprint(value)
 	case Error:
 		switch v := value.(type) {
 		case nil:
@@ -73,21 +74,62 @@ func (s *Span) SetTag(key string, value interface{}) ot.Span {
 		default:
 			s.Span.SetError(fmt.Errorf("%v", v))
 		}
+	case string(otext.Error):
+		// a boolean flagging the Span as failed, with no further details
+		s.Span.SetTag(ext.Error, value)
+	case string(otext.HTTPStatusCode):
+		s.Span.SetTag(ext.HTTPCode, value)
+		if code, err := strconv.ParseFloat(fmt.Sprint(value), 64); err == nil && code >= 500 && code < 600 {
+			s.Span.SetTag(ext.Error, true)
+		}
+	case string(otext.PeerHostname):
+		s.Span.SetMeta(ext.TargetHost, fmt.Sprint(value))
+	case string(otext.PeerPort):
+		s.Span.SetMeta(ext.TargetPort, fmt.Sprint(value))
+	case string(otext.DBStatement):
+		s.Span.SetTag(ext.ResourceName, value)
+	case string(otext.SpanKind):
+		s.setKind(fmt.Sprint(value))
 	default:
-		// NOTE: locking is not required because the `SetMeta` is
-		// already thread-safe
-		s.Span.SetMeta(key, fmt.Sprint(value))
+		s.Span.SetTag(key, value)
 	}
 	return s
 }
 
+// setKind stores the kind of the Span. The server and consumer Spans are the
+// entry points of a service, so trace stats are computed for them even when
+// they have a local parent, and the server Spans are web Spans unless their
+// type has been set.
+func (s *Span) setKind(kind string) {
+	s.Span.SetMeta(string(otext.SpanKind), kind)
+	switch otext.SpanKindEnum(kind) {
+	case otext.SpanKindRPCServerEnum:
+		s.Span.RLock()
+		typed := s.Span.Type != ""
+		s.Span.RUnlock()
+		if !typed {
+			s.Span.SetTag(ext.SpanType, ext.AppTypeWeb)
+		}
+		s.Span.SetMetric(ext.Measured, 1)
+	case otext.SpanKindConsumerEnum:
+		s.Span.SetMetric(ext.Measured, 1)
+	}
+}
+
 // FinishWithOptions is like Finish() but with explicit control over
 // timestamps and log data.
 func (s *Span) FinishWithOptions(options ot.FinishOptions) {
+	for _, record := range options.LogRecords {
+		s.logRecord(record.Timestamp, record.Fields)
+	}
+	for _, data := range options.BulkLogData {
+		s.Log(data)
+	}
 	if options.FinishTime.IsZero() {
-		options.FinishTime = time.Now().UTC()
+		// let the duration be measured with the monotonic clock
+		s.Span.Finish()
+		return
 	}
-
 	s.Span.FinishWithTime(options.FinishTime.UnixNano())
 }
 
@@ -104,29 +146,37 @@ func (s *Span) SetOperationName(operationName string) ot.Span {
 // logging data about a Span, though the programming interface is a little
 // more verbose than LogKV().
 func (s *Span) LogFields(fields ...log.Field) {
-	// TODO: implementation missing
+	s.logRecord(time.Now(), fields)
 }
 
 // LogKV is a concise, readable way to record key:value logging data about
 // a Span, though unfortunately this also makes it less efficient and less
 // type-safe than LogFields().
 func (s *Span) LogKV(keyVals ...interface{}) {
-	// TODO: implementation missing
+	fields, err := log.InterleavedKVToFields(keyVals...)
+	if err != nil {
+		// the malformed arguments are a misuse of LogKV, not an error of
+		// the operation, so they don't change the error status of the span
+		s.LogFields(log.String("error", err.Error()), log.String("function", "LogKV"))
+		return
+	}
+	s.LogFields(fields...)
 }
 
 // LogEvent is deprecated: use LogFields or LogKV
 func (s *Span) LogEvent(event string) {
-	// TODO: implementation missing
+	s.LogFields(log.String("event", event))
 }
 
 // LogEventWithPayload deprecated: use LogFields or LogKV
 func (s *Span) LogEventWithPayload(event string, payload interface{}) {
-	// TODO: implementation missing
+	s.LogFields(log.String("event", event), log.Object("payload", payload))
 }
 
 // Log is deprecated: use LogFields or LogKV
 func (s *Span) Log(data ot.LogData) {
-	// TODO: implementation missing
+	record := data.ToLogRecord()
+	s.logRecord(record.Timestamp, record.Fields)
 }
 
 // NewSpan is the OpenTracing Span constructor
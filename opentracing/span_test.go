@@ -6,6 +6,8 @@ import (
 	"time"
 
 	opentracing "github.com/opentracing/opentracing-go"
+	otext "github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -60,7 +62,77 @@ func TestSpanSetTag(t *testing.T) {
 	assert.Equal("tracer", span.Meta["component"])
 
 	span.SetTag("tagInt", 1234)
-	assert.Equal("1234", span.Meta["tagInt"])
+	assert.Equal("", span.Meta["tagInt"])
+	assert.Equal(float64(1234), span.Metrics["tagInt"])
+	span.SetTag("tagFloat", float32(0.5))
+	assert.Equal(0.5, span.Metrics["tagFloat"])
+	span.SetTag("tagBool", true)
+	assert.Equal("true", span.Meta["tagBool"])
+}
+
+func TestSpanSetStandardTags(t *testing.T) {
+	assert := assert.New(t)
+	span := NewSpan("web.request")
+
+	otext.SpanKindRPCServer.Set(span)
+	otext.PeerHostname.Set(span, "db.local")
+	otext.PeerPort.Set(span, 5432)
+	otext.DBStatement.Set(span, "SELECT * FROM users")
+	otext.SamplingPriority.Set(span, 2)
+	otext.HTTPStatusCode.Set(span, 200)
+
+	assert.Equal("server", span.Meta["span.kind"])
+	assert.Equal("db.local", span.Meta["out.host"])
+	assert.Equal("5432", span.Meta["out.port"])
+	assert.Equal("SELECT * FROM users", span.Span.Resource)
+	assert.True(span.Span.HasSamplingPriority())
+	assert.Equal(2, span.Span.GetSamplingPriority())
+	assert.Equal("200", span.Meta["http.status_code"])
+	assert.Equal(int32(0), span.Span.Error)
+	for _, key := range []string{"peer.hostname", "peer.port", "db.statement", "sampling.priority"} {
+		assert.NotContains(span.Meta, key)
+		assert.NotContains(span.Metrics, key)
+	}
+
+	// server and consumer spans are measured, server spans are web spans
+	assert.Equal(float64(1), span.Metrics["_dd.measured"])
+	assert.Equal("web", span.Span.Type)
+	span = NewSpan("kafka.consume")
+	span.Span.Type = "queue"
+	otext.SpanKindConsumer.Set(span)
+	assert.Equal("consumer", span.Meta["span.kind"])
+	assert.Equal(float64(1), span.Metrics["_dd.measured"])
+	assert.Equal("queue", span.Span.Type)
+	span = NewSpan("grpc.client")
+	otext.SpanKindRPCClient.Set(span)
+	assert.Equal("client", span.Meta["span.kind"])
+	assert.NotContains(span.Metrics, "_dd.measured")
+	assert.Equal("", span.Span.Type)
+
+	// 5xx status codes flag the span as failed
+	span = NewSpan("web.request")
+	span.SetTag("http.status_code", "503")
+	assert.Equal("503", span.Meta["http.status_code"])
+	assert.Equal(int32(1), span.Span.Error)
+
+	// the error tag flags the span as failed, without error details
+	span = NewSpan("web.request")
+	otext.Error.Set(span, true)
+	assert.Equal(int32(1), span.Span.Error)
+	assert.Equal("", span.Meta["error.msg"])
+	otext.Error.Set(span, false)
+	assert.Equal(int32(0), span.Span.Error)
+
+	// finished spans are not modified
+	span = NewSpan("web.request")
+	span.SetTag("resource.name", "/")
+	span.Finish()
+	otext.Error.Set(span, true)
+	otext.DBStatement.Set(span, "SELECT 1")
+	span.SetTag("http.status_code", 500)
+	assert.Equal(int32(0), span.Span.Error)
+	assert.Equal("/", span.Span.Resource)
+	assert.NotContains(span.Meta, "http.status_code")
 }
 
 func TestSpanSetDatadogTags(t *testing.T) {
@@ -127,3 +199,114 @@ func TestSpanSetErrorTag(t *testing.T) {
 		}
 	}
 }
+
+func TestSpanLogFields(t *testing.T) {
+	assert := assert.New(t)
+	span := NewSpan("web.request")
+	before := time.Now().UnixNano()
+	span.LogFields(log.String("event", "cache miss"), log.Int("retry", 2), log.Bool("hit", false))
+	span.LogKV("key", "value", "ratio", 0.5)
+	span.LogEvent("done")
+	span.LogEventWithPayload("payload", struct{ N int }{5})
+	span.Finish()
+
+	events := span.Events
+	assert.Len(events, 4)
+	assert.Equal("cache miss", events[0].Name)
+	assert.True(events[0].TimeUnixNano >= before)
+	assert.Equal(map[string]interface{}{"retry": 2, "hit": false}, events[0].Attributes)
+	assert.Equal("log", events[1].Name)
+	assert.Equal(map[string]interface{}{"key": "value", "ratio": 0.5}, events[1].Attributes)
+	assert.Equal("done", events[2].Name)
+	assert.Nil(events[2].Attributes)
+	assert.Equal("payload", events[3].Name)
+	assert.Equal(map[string]interface{}{"payload": "{5}"}, events[3].Attributes)
+	assert.Equal(int32(0), span.Error)
+}
+
+func TestSpanLogFinishWithOptions(t *testing.T) {
+	assert := assert.New(t)
+	span := NewSpan("web.request")
+	ts := time.Now().Add(-time.Second)
+	span.FinishWithOptions(opentracing.FinishOptions{
+		LogRecords: []opentracing.LogRecord{
+			{Timestamp: ts, Fields: []log.Field{log.String("event", "record")}},
+		},
+		BulkLogData: []opentracing.LogData{
+			{Timestamp: ts, Event: "bulk"},
+		},
+	})
+
+	events := span.Events
+	assert.Len(events, 2)
+	assert.Equal("record", events[0].Name)
+	assert.Equal(ts.UnixNano(), events[0].TimeUnixNano)
+	assert.Equal("bulk", events[1].Name)
+}
+
+func TestSpanLogError(t *testing.T) {
+	assert := assert.New(t)
+
+	span := NewSpan("web.request")
+	span.LogFields(log.String("event", "error"), log.Object("error.object", errors.New("some error")))
+	assert.Equal(int32(1), span.Error)
+	assert.Equal("some error", span.Meta["error.msg"])
+	assert.Equal("*errors.errorString", span.Meta["error.type"])
+
+	span = NewSpan("web.request")
+	span.LogKV("event", "error", "message", "boom", "error.kind", "Timeout", "stack", "main.go:12")
+	assert.Equal(int32(1), span.Error)
+	assert.Equal("boom", span.Meta["error.msg"])
+	assert.Equal("Timeout", span.Meta["error.type"])
+	assert.Equal("main.go:12", span.Meta["error.stack"])
+	span.Finish()
+	events := span.Events
+	assert.Len(events, 1)
+	assert.Equal("error", events[0].Name)
+
+	// an error object sets the error, whatever the event
+	span = NewSpan("web.request")
+	span.LogFields(log.Error(errors.New("not an error event")))
+	assert.Equal(int32(1), span.Error)
+	assert.Equal("not an error event", span.Meta["error.msg"])
+
+	// other log records don't change the error status
+	span = NewSpan("web.request")
+	span.LogKV("event", "retry", "error.object", "not an error")
+	assert.Equal(int32(0), span.Error)
+
+	// malformed LogKV arguments are logged, without setting the error
+	span = NewSpan("web.request")
+	span.LogKV("event", "retry", "odd")
+	assert.Equal(int32(0), span.Error)
+	span.Finish()
+	events = span.Events
+	if assert.Len(events, 1) {
+		assert.Equal("LogKV", events[0].Attributes["function"])
+		assert.Equal("non-even keyValues len: 3", events[0].Attributes["error"])
+	}
+}
+
+func TestSpanLogLimits(t *testing.T) {
+	assert := assert.New(t)
+	span := NewSpan("web.request")
+	for i := 0; i < 110; i++ {
+		span.LogKV("i", i)
+	}
+	long := make([]byte, maxLogValueLen*2)
+	for i := range long {
+		long[i] = 'a'
+	}
+	span.LogFields(log.String("long", string(long)))
+	span.Finish()
+
+	events := span.Events
+	assert.Len(events, 100)
+	assert.Equal(float64(11), span.Metrics["_dd.span_events.dropped"])
+
+	span = NewSpan("web.request")
+	span.LogFields(log.String("long", string(long)))
+	span.Finish()
+	events = span.Events
+	assert.Equal(string(long[:maxLogValueLen])+"...", events[0].Attributes["long"])
+}
@@ -3,7 +3,6 @@ package opentracing
 import (
 	"errors"
 	"io"
-	"time"
 
 	ddtrace "github.com/DataDog/dd-trace-go/tracer"
 	ot "github.com/opentracing/opentracing-go"
@@ -23,6 +22,10 @@ type Tracer struct {
 // StartSpan creates, starts, and returns a new Span with the given `operationName`
 // A Span with no SpanReference options (e.g., opentracing.ChildOf() or
 // opentracing.FollowsFrom()) becomes the root of its own trace.
+//
+// The parent of the Span is its first ChildOf reference or, if there is
+// none, its first FollowsFrom reference; the Span is then tagged with the
+// "span.reference" tag. All the other references are recorded as span links.
 func (t *Tracer) StartSpan(operationName string, options ...ot.StartSpanOption) ot.Span {
 	sso := ot.StartSpanOptions{}
 	for _, o := range options {
@@ -33,42 +36,49 @@ func (t *Tracer) StartSpan(operationName string, options ...ot.StartSpanOption)
 }
 
 func (t *Tracer) startSpanWithOptions(operationName string, options ot.StartSpanOptions) ot.Span {
-	if options.StartTime.IsZero() {
-		options.StartTime = time.Now().UTC()
-	}
-
 	var context SpanContext
 	var hasParent bool
 	var parent *Span
 	var span *ddtrace.Span
 
-	for _, ref := range options.References {
-		ctx, ok := ref.ReferencedContext.(SpanContext)
-		if !ok {
+	// the parent is the first ChildOf reference or, if there is none, the
+	// first FollowsFrom reference; every other reference becomes a link
+	parentRef := -1
+	for i, ref := range options.References {
+		if _, ok := ref.ReferencedContext.(SpanContext); !ok {
 			// ignore the SpanContext since it's not valid
 			continue
 		}
-
-		// if we have parenting define it
 		if ref.Type == ot.ChildOfRef {
-			hasParent = true
-			context = ctx
-			parent = ctx.span
+			parentRef = i
+			break
 		}
+		if ref.Type == ot.FollowsFromRef && parentRef == -1 {
+			parentRef = i
+		}
+	}
+
+	if parentRef != -1 {
+		// if we have parenting define it
+		hasParent = true
+		context = options.References[parentRef].ReferencedContext.(SpanContext)
+		parent = context.span
 	}
 
-	if parent == nil {
+	if parent == nil && hasParent {
+		// the Context doesn't have a Span reference because it has been
+		// propagated from another process, so the trace is continued from
+		// the propagated IDs, sampling priority and origin
+		span = t.impl.NewRemoteChildSpan(operationName, t.config.ServiceName, operationName, ddtrace.RemoteParent{
+			TraceID:     context.traceID,
+			SpanID:      context.spanID,
+			Priority:    context.priority,
+			HasPriority: context.hasPriority,
+			Origin:      context.origin,
+		})
+	} else if parent == nil {
 		// create a root Span with the default service name and resource
 		span = t.impl.NewRootSpan(operationName, t.config.ServiceName, operationName)
-
-		if hasParent {
-			// the Context doesn't have a Span reference because it
-			// has been propagated from another process, so we set these
-			// values manually
-			span.TraceID = context.traceID
-			span.ParentID = context.spanID
-			t.impl.Sample(span)
-		}
 	} else {
 		// create a child Span that inherits from a parent
 		span = t.impl.NewChildSpan(operationName, parent.Span)
@@ -88,16 +98,26 @@ func (t *Tracer) startSpanWithOptions(operationName string, options ot.StartSpan
 	}
 	otSpan.context.span = otSpan
 
-	// set start time
-	otSpan.Span.Start = options.StartTime.UnixNano()
+	// set start time; by default the Span started when it was created,
+	// which lets its duration be measured with the monotonic clock
+	if !options.StartTime.IsZero() {
+		otSpan.Span.Start = options.StartTime.UnixNano()
+	}
 
-	if parent != nil {
-		// propagate baggage items
-		if l := len(parent.context.baggage); l > 0 {
-			otSpan.context.baggage = make(map[string]string, len(parent.context.baggage))
-			for k, v := range parent.context.baggage {
-				otSpan.context.baggage[k] = v
-			}
+	// the baggage of a local parent is inherited by the Datadog Span, and
+	// the baggage held by the SpanContext, propagated from another process
+	// or set with WithBaggageItem, is copied to it
+	for k, v := range context.baggage {
+		span.SetBaggageItem(k, v)
+	}
+
+	if hasParent && options.References[parentRef].Type != ot.ChildOfRef {
+		// keep track of the relationship with the parent
+		otSpan.SetTag(referenceKey, referenceName(options.References[parentRef].Type))
+	}
+	for i, ref := range options.References {
+		if ctx, ok := ref.ReferencedContext.(SpanContext); ok && i != parentRef {
+			otSpan.Span.AddLink(ctx.traceID, ctx.spanID, map[string]string{referenceAttr: referenceName(ref.Type)})
 		}
 	}
 
@@ -119,10 +139,13 @@ func (t *Tracer) startSpanWithOptions(operationName string, options ot.StartSpan
 // the value of `format`. Currently supported Injectors are:
 // * `TextMap`
 // * `HTTPHeaders`
+// * `Binary`
 func (t *Tracer) Inject(ctx ot.SpanContext, format interface{}, carrier interface{}) error {
 	switch format {
 	case ot.TextMap, ot.HTTPHeaders:
 		return t.config.TextMapPropagator.Inject(ctx, carrier)
+	case ot.Binary:
+		return t.config.BinaryPropagator.Inject(ctx, carrier)
 	}
 	return ot.ErrUnsupportedFormat
 }
@@ -132,6 +155,8 @@ func (t *Tracer) Extract(format interface{}, carrier interface{}) (ot.SpanContex
 	switch format {
 	case ot.TextMap, ot.HTTPHeaders:
 		return t.config.TextMapPropagator.Extract(carrier)
+	case ot.Binary:
+		return t.config.BinaryPropagator.Extract(carrier)
 	}
 	return nil, ot.ErrUnsupportedFormat
 }
@@ -167,6 +192,7 @@ func NewTracer(config *Configuration) (ot.Tracer, io.Closer, error) {
 	}
 	tracer.impl.SetDebugLogging(config.Debug)
 	tracer.impl.SetSampleRate(config.SampleRate)
+	tracer.impl.SetStatsComputation(config.ComputeStats)
 
 	// set the new Datadog Tracer as a `DefaultTracer` so it can be
 	// used in integrations. NOTE: this is a temporary implementation
@@ -68,7 +68,7 @@ func TestTracerBaggagePropagation(t *testing.T) {
 	context, ok := child.Context().(SpanContext)
 	assert.True(ok)
 
-	assert.Equal("value", context.baggage["key"])
+	assert.Equal("value", context.baggageItems()["key"])
 }
 
 func TestTracerBaggageImmutability(t *testing.T) {
@@ -86,8 +86,8 @@ func TestTracerBaggageImmutability(t *testing.T) {
 	childContext, ok := child.Context().(SpanContext)
 	assert.True(ok)
 
-	assert.Equal("value", parentContext.baggage["key"])
-	assert.Equal("changed!", childContext.baggage["key"])
+	assert.Equal("value", parentContext.baggageItems()["key"])
+	assert.Equal("changed!", childContext.baggageItems()["key"])
 }
 
 func TestTracerSpanTags(t *testing.T) {
@@ -129,3 +129,92 @@ func TestTracerSpanStartTime(t *testing.T) {
 
 	assert.Equal(startTime.UnixNano(), span.Span.Start)
 }
+
+func TestTracerStartFollowsFromSpan(t *testing.T) {
+	assert := assert.New(t)
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+
+	root := tracer.StartSpan("web.request").(*Span)
+	root.SetBaggageItem("key", "value")
+	child := tracer.StartSpan("async.job", opentracing.FollowsFrom(root.Context())).(*Span)
+
+	assert.Equal(root.Span.TraceID, child.Span.TraceID)
+	assert.Equal(root.Span.SpanID, child.Span.ParentID)
+	assert.Equal("follows_from", child.Span.Meta["span.reference"])
+	assert.Equal("value", child.BaggageItem("key"))
+	assert.Len(child.Span.Links, 0)
+
+	// ChildOf references have precedence over FollowsFrom ones
+	other := tracer.StartSpan("web.request").(*Span)
+	child = tracer.StartSpan("db.query",
+		opentracing.FollowsFrom(other.Context()),
+		opentracing.ChildOf(root.Context()),
+	).(*Span)
+	assert.Equal(root.Span.TraceID, child.Span.TraceID)
+	assert.Equal(root.Span.SpanID, child.Span.ParentID)
+	assert.Equal("", child.Span.Meta["span.reference"])
+
+	assert.Equal([]ddtrace.SpanLink{{
+		TraceID:    other.Span.TraceID,
+		SpanID:     other.Span.SpanID,
+		Attributes: map[string]string{"reference": "follows_from"},
+	}}, child.Span.Links)
+}
+
+func TestTracerStartSpanMultipleReferences(t *testing.T) {
+	assert := assert.New(t)
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+
+	// a batch consumer links every producer, the first one being its parent
+	var refs []opentracing.StartSpanOption
+	var producers []*Span
+	for i := 0; i < 3; i++ {
+		producer := tracer.StartSpan("kafka.produce").(*Span)
+		producers = append(producers, producer)
+		refs = append(refs, opentracing.ChildOf(producer.Context()))
+	}
+	// remote references are linked too
+	refs = append(refs, opentracing.FollowsFrom(SpanContext{traceID: 1, spanID: 2}))
+	consumer := tracer.StartSpan("kafka.consume", refs...).(*Span)
+
+	assert.Equal(producers[0].Span.TraceID, consumer.Span.TraceID)
+	assert.Equal(producers[0].Span.SpanID, consumer.Span.ParentID)
+
+	links := consumer.Span.Links
+	assert.Len(links, 3)
+	for i, producer := range producers[1:] {
+		assert.Equal(producer.Span.TraceID, links[i].TraceID)
+		assert.Equal(producer.Span.SpanID, links[i].SpanID)
+		assert.Equal("child_of", links[i].Attributes["reference"])
+	}
+	assert.Equal(uint64(1), links[2].TraceID)
+	assert.Equal(uint64(2), links[2].SpanID)
+	assert.Equal("follows_from", links[2].Attributes["reference"])
+}
+
+func TestAddSpanLink(t *testing.T) {
+	assert := assert.New(t)
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+
+	producer := tracer.StartSpan("kafka.produce")
+	carrier := opentracing.TextMapCarrier{}
+	assert.Nil(tracer.Inject(producer.Context(), opentracing.TextMap, carrier))
+	ctx, err := tracer.Extract(opentracing.TextMap, carrier)
+	assert.Nil(err)
+
+	consumer := tracer.StartSpan("kafka.consume")
+	assert.True(AddSpanLink(consumer, ctx, map[string]string{"topic": "orders"}))
+	assert.Equal([]ddtrace.SpanLink{{
+		TraceID:    producer.(*Span).Span.TraceID,
+		SpanID:     producer.(*Span).Span.SpanID,
+		Attributes: map[string]string{"topic": "orders"},
+	}}, consumer.(*Span).Span.Links)
+
+	// foreign spans and contexts are ignored
+	assert.False(AddSpanLink(opentracing.NoopTracer{}.StartSpan("noop"), ctx, nil))
+	assert.False(AddSpanLink(consumer, opentracing.NoopTracer{}.StartSpan("noop").Context(), nil))
+	assert.Len(consumer.(*Span).Span.Links, 1)
+}
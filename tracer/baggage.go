@@ -0,0 +1,52 @@
+package tracer
+
+// SetBaggageItem sets a baggage item on the span. Baggage items are
+// inherited by the children created afterwards, and propagated to other
+// processes along with the trace ids by the integrations. They must be
+// used sparingly, since they are sent with every request.
+// If the Span has been finished, it will not be modified by this method.
+func (s *Span) SetBaggageItem(key, value string) {
+	if s == nil {
+		return
+	}
+	if !s.lockMutable() {
+		return
+	}
+	defer s.Unlock()
+
+	// copy on write, as the current map may be shared with the parent
+	// and the children of the span
+	baggage := make(map[string]string, len(s.baggage)+1)
+	for k, v := range s.baggage {
+		baggage[k] = v
+	}
+	baggage[key] = value
+	s.baggage = baggage
+}
+
+// BaggageItem returns the value of the given baggage item, or the empty
+// string if it has not been set.
+func (s *Span) BaggageItem(key string) string {
+	if s == nil {
+		return ""
+	}
+	s.RLock()
+	defer s.RUnlock()
+	return s.baggage[key]
+}
+
+// ForeachBaggageItem calls handler with each baggage item of the span,
+// until it returns false.
+func (s *Span) ForeachBaggageItem(handler func(key, value string) bool) {
+	if s == nil {
+		return
+	}
+	s.RLock()
+	baggage := s.baggage
+	s.RUnlock()
+	for k, v := range baggage {
+		if !handler(k, v) {
+			return
+		}
+	}
+}
@@ -2,6 +2,9 @@ package tracer
 
 import (
 	"sync"
+	"sync/atomic"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
 )
 
 const (
@@ -23,6 +26,15 @@ type spanBuffer struct {
 	spans         []*Span
 	finishedSpans int
 
+	// abandoned is true once the trace has been flushed by the leak
+	// detector, the spans pushed or finished later being dropped.
+	abandoned bool
+
+	// priority is the sampling priority set on the whole trace with
+	// Span.Keep or Span.Drop, if hasPriority is true.
+	priority    int
+	hasPriority bool
+
 	initSize int
 	maxSize  int
 
@@ -48,19 +60,31 @@ func (tb *spanBuffer) Push(span *Span) {
 		return
 	}
 
+	// the error is pushed once the buffer is unlocked, since the OnError
+	// hook of the tracer may use the trace
+	if err := tb.push(span); err != nil {
+		tb.channels.pushErr(err)
+	}
+}
+
+// push implements Push, returning the error to report if the span is
+// dropped.
+func (tb *spanBuffer) push(span *Span) error {
 	tb.Lock()
 	defer tb.Unlock()
 
+	if tb.abandoned {
+		return nil
+	}
 	if len(tb.spans) > 0 {
 		// if spanBuffer is full, forget span
 		if len(tb.spans) >= tb.maxSize {
-			tb.channels.pushErr(&errorSpanBufFull{Len: len(tb.spans)})
-			return
+			atomic.AddUint64(&tb.channels.counters.droppedBufferFull, 1)
+			return &ErrorSpanBufFull{Len: len(tb.spans)}
 		}
 		// if there's a trace ID mismatch, ignore span
 		if tb.spans[0].TraceID != span.TraceID {
-			tb.channels.pushErr(&errorTraceIDMismatch{Expected: tb.spans[0].TraceID, Actual: span.TraceID})
-			return
+			return &ErrorTraceIDMismatch{Expected: tb.spans[0].TraceID, Actual: span.TraceID}
 		}
 	}
 
@@ -69,6 +93,7 @@ func (tb *spanBuffer) Push(span *Span) {
 	}
 
 	tb.spans = append(tb.spans, span)
+	return nil
 }
 
 func (tb *spanBuffer) flushable() bool {
@@ -86,6 +111,9 @@ func (tb *spanBuffer) ack() {
 	tb.Lock()
 	defer tb.Unlock()
 
+	if tb.abandoned {
+		return
+	}
 	tb.finishedSpans++
 }
 
@@ -95,11 +123,78 @@ func (tb *spanBuffer) doFlush() {
 	}
 
 	tb.Lock()
-	defer tb.Unlock()
-
-	tb.channels.pushTrace(tb.spans)
+	spans := tb.spans
+	priority, hasPriority := tb.priority, tb.hasPriority
 	tb.spans = nil
 	tb.finishedSpans = 0 // important, because a buffer can be used for several flushes
+	tb.Unlock()
+
+	if len(spans) == 0 {
+		return
+	}
+	if !sampleTrace(spans, priority, hasPriority) {
+		atomic.AddUint64(&tb.channels.counters.droppedSampler, 1)
+		return
+	}
+	tb.channels.pushTrace(spans)
+}
+
+// sampleTrace returns true if the given trace must be kept. The sampling
+// priority set on the trace with Span.Keep or Span.Drop, if any, overrides
+// the decision the sampler took when the trace started, and is set on its
+// local root. Otherwise, the tail-based sampling can keep the trace anyway,
+// see TailSampling.
+func sampleTrace(spans []*Span, priority int, hasPriority bool) bool {
+	// a buffer can be flushed several times, so the spans don't always
+	// start with the local root, which may have been flushed before
+	root := spans[0].localRoot()
+	cfg := root.tracer.TailSampling()
+	var reason string
+	if cfg.Enabled && !hasPriority {
+		reason = cfg.keepReason(root, spans)
+	}
+
+	root.RLock()
+	sampled := root.Sampled
+	root.RUnlock()
+	switch {
+	case hasPriority:
+		sampled = priority > ext.PriorityAutoReject
+		reason = keepReasonManual
+	case reason != "":
+		if !sampled {
+			sampled = true
+			priority, hasPriority = ext.PriorityAutoKeep, true
+		}
+	default:
+		reason = keepReasonRate
+	}
+
+	// the decision is recorded on the local root, or on the first span if
+	// the local root has been flushed before and must not be modified
+	target := spans[0]
+	for _, s := range spans {
+		if s == root {
+			target = root
+			break
+		}
+	}
+	target.Lock()
+	defer target.Unlock()
+	target.Sampled = sampled
+	if hasPriority {
+		if target.Metrics == nil {
+			target.Metrics = make(map[string]float64)
+		}
+		target.Metrics[samplingPriorityKey] = float64(priority)
+	}
+	if cfg.Enabled && sampled {
+		if target.Meta == nil {
+			target.Meta = make(map[string]string)
+		}
+		target.Meta[keepReasonKey] = reason
+	}
+	return sampled
 }
 
 func (tb *spanBuffer) Flush() {
@@ -117,6 +212,38 @@ func (tb *spanBuffer) AckFinish() {
 	tb.doFlush()
 }
 
+// flushed returns true if the spans of the buffer have been flushed, or
+// abandoned by the leak detector.
+func (tb *spanBuffer) flushed() bool {
+	if tb == nil {
+		return false
+	}
+	tb.RLock()
+	defer tb.RUnlock()
+	return tb.abandoned || len(tb.spans) == 0
+}
+
+// setPriority sets the sampling priority of the whole trace.
+func (tb *spanBuffer) setPriority(priority int) {
+	if tb == nil {
+		return
+	}
+	tb.Lock()
+	tb.priority, tb.hasPriority = priority, true
+	tb.Unlock()
+}
+
+// samplingPriority returns the sampling priority set on the whole trace, and
+// whether one is set.
+func (tb *spanBuffer) samplingPriority() (int, bool) {
+	if tb == nil {
+		return 0, false
+	}
+	tb.RLock()
+	defer tb.RUnlock()
+	return tb.priority, tb.hasPriority
+}
+
 func (tb *spanBuffer) Len() int {
 	if tb == nil {
 		return 0
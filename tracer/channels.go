@@ -1,5 +1,16 @@
 package tracer
 
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// errorHandler wraps the error handler of a tracer, see Tracer.OnError,
+// since an atomic.Value can't hold a nil value.
+type errorHandler struct {
+	onError func(error)
+}
+
 const (
 	// traceChanLen is the capacity of the trace channel. This channels is emptied
 	// on a regular basis (worker thread) or when it reaches 50% of its capacity.
@@ -20,7 +31,7 @@ const (
 
 // traceChans holds most tracer channels together, it's mostly used to
 // pass them together to the span buffer/context. It's obviously safe
-// to access it concurrently as it contains channels only. And it's convenient
+// to access it concurrently as it contains channels and atomic values only. And it's convenient
 // to have it isolated from tracer, for the sake of unit testing.
 type tracerChans struct {
 	trace        chan []*Span
@@ -29,6 +40,20 @@ type tracerChans struct {
 	traceFlush   chan struct{}
 	serviceFlush chan struct{}
 	errFlush     chan struct{}
+
+	counters *tracerCounters
+	onError  *atomic.Value // errorHandler called with every error pushed
+
+	// closed is set once the worker has exited, the traces pushed later
+	// being dropped.
+	closed *closeFlag
+}
+
+// closeFlag tells whether the trace channel is closed. The traces are pushed
+// with its read lock held, so that none is queued once it has been drained.
+type closeFlag struct {
+	sync.RWMutex
+	closed bool
 }
 
 func newTracerChans() tracerChans {
@@ -39,10 +64,29 @@ func newTracerChans() tracerChans {
 		traceFlush:   make(chan struct{}, 1),
 		serviceFlush: make(chan struct{}, 1),
 		errFlush:     make(chan struct{}, 1),
+		counters:     &tracerCounters{},
+		onError:      &atomic.Value{},
+		closed:       &closeFlag{},
 	}
 }
 
 func (tc *tracerChans) pushTrace(trace []*Span) {
+	// the error is pushed once the flag is unlocked, since the OnError hook
+	// of the tracer may push traces too
+	if err := tc.queueTrace(trace); err != nil {
+		tc.pushErr(err)
+	}
+}
+
+// queueTrace queues the trace, unless the trace channel is closed or full.
+func (tc *tracerChans) queueTrace(trace []*Span) error {
+	tc.closed.RLock()
+	defer tc.closed.RUnlock()
+	if tc.closed.closed {
+		// nothing will ever send it
+		atomic.AddUint64(&tc.counters.droppedShutdown, 1)
+		return nil
+	}
 	if len(tc.trace) >= cap(tc.trace)/2 { // starts being full, anticipate, try and flush soon
 		select {
 		case tc.traceFlush <- struct{}{}:
@@ -51,8 +95,28 @@ func (tc *tracerChans) pushTrace(trace []*Span) {
 	}
 	select {
 	case tc.trace <- trace:
+		return nil
 	default: // never block user code
-		tc.pushErr(&errorTraceChanFull{Len: len(tc.trace)})
+		atomic.AddUint64(&tc.counters.droppedChanFull, 1)
+		return &ErrorTraceChanFull{Len: len(tc.trace)}
+	}
+}
+
+// close makes the traces pushed from now on dropped, along with the ones
+// still queued, which are counted as dropped on shutdown. It is called once
+// the worker has flushed for the last time, or when the shutdown is given up.
+// It can be called several times.
+func (tc *tracerChans) close() {
+	tc.closed.Lock()
+	defer tc.closed.Unlock()
+	tc.closed.closed = true
+	for {
+		select {
+		case <-tc.trace:
+			atomic.AddUint64(&tc.counters.droppedShutdown, 1)
+		default:
+			return
+		}
 	}
 }
 
@@ -66,11 +130,14 @@ func (tc *tracerChans) pushService(service Service) {
 	select {
 	case tc.service <- service:
 	default: // never block user code
-		tc.pushErr(&errorServiceChanFull{Len: len(tc.service)})
+		tc.pushErr(&ErrorServiceChanFull{Len: len(tc.service)})
 	}
 }
 
 func (tc *tracerChans) pushErr(err error) {
+	if h, ok := tc.onError.Load().(errorHandler); ok && h.onError != nil {
+		h.onError(err)
+	}
 	if len(tc.err) >= cap(tc.err)/2 { // starts being full, anticipate, try and flush soon
 		select {
 		case tc.errFlush <- struct{}{}:
@@ -2,6 +2,7 @@ package tracer
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -45,7 +46,31 @@ func TestPushTrace(t *testing.T) {
 	assert.Len(channels.trace, traceChanLen, "buffer should be full")
 	assert.NotEqual(0, len(channels.err), "there should be an error logged")
 	err := <-channels.err
-	assert.Equal(&errorTraceChanFull{Len: traceChanLen}, err)
+	assert.Equal(&ErrorTraceChanFull{Len: traceChanLen}, err)
+}
+
+func TestPushTraceClose(t *testing.T) {
+	assert := assert.New(t)
+
+	channels := newTracerChans()
+
+	// every trace pushed concurrently with close is counted as dropped,
+	// whether it was queued before the channel was drained or pushed after
+	const pushed = 100
+	var wg sync.WaitGroup
+	for i := 0; i < pushed; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			channels.pushTrace([]*Span{&Span{}})
+		}()
+	}
+	channels.close()
+	wg.Wait()
+	channels.close()
+
+	assert.Len(channels.trace, 0)
+	assert.Equal(uint64(pushed), channels.counters.droppedShutdown)
 }
 
 func TestPushService(t *testing.T) {
@@ -87,7 +112,7 @@ func TestPushService(t *testing.T) {
 	assert.Len(channels.service, serviceChanLen, "buffer should be full")
 	assert.NotEqual(0, len(channels.err), "there should be an error logged")
 	err := <-channels.err
-	assert.Equal(&errorServiceChanFull{Len: serviceChanLen}, err)
+	assert.Equal(&ErrorServiceChanFull{Len: serviceChanLen}, err)
 }
 
 func TestPushErr(t *testing.T) {
@@ -0,0 +1,51 @@
+package tracer
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock gives the current time to a Tracer. It can be replaced by a fake
+// clock to make the timings of the spans deterministic in tests.
+type Clock interface {
+	// Now returns the current time. If it has a monotonic clock reading,
+	// it is used to measure the duration of the spans, so that they don't
+	// depend on wall clock changes.
+	Now() time.Time
+}
+
+// systemClock is the default Clock, see time.go.
+type systemClock struct{}
+
+// clockLog logs which implementation of the system clock is used, if it has
+// to, see time_windows.go. It is set when the package is initialized, before
+// a logger can be installed, so it is called by the first flush of a tracer,
+// with the logger of that tracer.
+var (
+	clockLog     func(Logger)
+	clockLogOnce sync.Once
+)
+
+// clockHolder wraps the Clock of a tracer, since an atomic.Value must always
+// hold values of the same concrete type.
+type clockHolder struct{ Clock }
+
+// SetClock sets the clock used to time the spans. A nil clock restores the
+// system one.
+func (t *Tracer) SetClock(clock Clock) {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	t.clock.Store(clockHolder{clock})
+}
+
+// Clock returns the clock used to time the spans.
+func (t *Tracer) Clock() Clock {
+	if t == nil { // Defensive, span could be initialized with nil tracer
+		return systemClock{}
+	}
+	if h, ok := t.clock.Load().(clockHolder); ok {
+		return h.Clock
+	}
+	return systemClock{}
+}
@@ -0,0 +1,82 @@
+package tracer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a Clock whose time only changes when advanced.
+type fakeClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestTracerClock(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	assert.Equal(systemClock{}, tracer.Clock())
+
+	start := time.Unix(1500000000, 0)
+	clock := &fakeClock{now: start}
+	tracer.SetClock(clock)
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	clock.Advance(time.Millisecond)
+	child := tracer.NewChildSpan("redis.command", root)
+	clock.Advance(3 * time.Millisecond)
+	child.AddEvent("cache miss", nil)
+	clock.Advance(2 * time.Millisecond)
+	child.Finish()
+	clock.Advance(4 * time.Millisecond)
+	root.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal(start.UnixNano(), root.Start)
+	assert.Equal(int64(10*time.Millisecond), root.Duration)
+	assert.Equal(start.Add(time.Millisecond).UnixNano(), child.Start)
+	assert.Equal(int64(5*time.Millisecond), child.Duration)
+	assert.Equal(start.Add(4*time.Millisecond).UnixNano(), child.Events[0].TimeUnixNano)
+
+	tracer.SetClock(nil)
+	assert.Equal(systemClock{}, tracer.Clock())
+}
+
+func TestSpanDuration(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+
+	// explicit finish times are wall clock times
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.FinishWithTime(span.Start + int64(time.Second))
+	assert.Equal(int64(time.Second), span.Duration)
+
+	// otherwise durations are measured with the monotonic clock
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	time.Sleep(time.Millisecond)
+	span.Finish()
+	assert.True(span.Duration >= int64(time.Millisecond))
+
+	// if the start time is changed, the wall clock is used
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.Start -= int64(time.Hour)
+	span.Finish()
+	assert.True(span.Duration >= int64(time.Hour))
+}
@@ -0,0 +1,118 @@
+package tracer
+
+import (
+	"expvar"
+	"sync/atomic"
+	"time"
+)
+
+// tracerCounters holds the counters of a tracer. They are only set
+// atomically, and are kept in their own struct, always allocated on its
+// own, so that they are 64-bit aligned on 32-bit platforms. The spans and
+// the span buffers reach them through the tracer channels.
+type tracerCounters struct {
+	spansStarted  uint64
+	spansFinished uint64
+	tracesFlushed uint64
+
+	// traces dropped, by reason; droppedBufferFull counts spans, since
+	// the trace they belong to is still sent
+	droppedChanFull   uint64
+	droppedBufferFull uint64
+	droppedSampler    uint64
+	droppedTransport  uint64
+	droppedShutdown   uint64
+
+	// sendingTraces is the number of traces being sent
+	sendingTraces uint64
+
+	// values truncated and tags dropped because of the span limits
+	truncatedValues uint64
+	droppedTags     uint64
+}
+
+// flushResult is the outcome of the last attempt to send traces.
+type flushResult struct {
+	time time.Time
+	err  string
+}
+
+// apiVersioner is implemented by the transports which are able to tell
+// the version of the agent API they target.
+type apiVersioner interface {
+	APIVersion() string
+}
+
+// Stats is a snapshot of the runtime statistics of a tracer.
+type Stats struct {
+	SpansStarted  uint64 // spans created
+	SpansFinished uint64 // spans finished
+	TracesFlushed uint64 // traces successfully sent to the agent
+
+	// TracesDropped holds the traces dropped, by reason.
+	TracesDropped DroppedTraces
+
+	TruncatedValues uint64 // tag values and resources truncated because of the span limits
+	DroppedTags     uint64 // tags dropped because of the span limits
+
+	LastFlush      time.Time // time of the last attempt to send traces, zero if none
+	LastFlushError string    // error of the last attempt to send traces, empty if it succeeded
+	QueueDepth     int       // finished traces waiting to be sent
+	APIVersion     string    // version of the agent API negotiated by the transport, if known
+}
+
+// DroppedTraces counts the traces that were dropped by a tracer.
+type DroppedTraces struct {
+	ChannelFull uint64 // the queue of traces to send was full
+	BufferFull  uint64 // spans dropped because their trace had too many spans
+	Sampler     uint64 // the trace was not sampled
+	Transport   uint64 // the trace could not be sent to the agent
+	Shutdown    uint64 // the trace was finished once the tracer was stopped
+}
+
+// Stats returns a snapshot of the runtime statistics of the tracer. It is
+// safe to call it concurrently, and the statistics of the DefaultTracer are
+// also published with expvar, as "datadog.tracer".
+func (t *Tracer) Stats() Stats {
+	c := t.channels.counters
+	stats := Stats{
+		SpansStarted:  atomic.LoadUint64(&c.spansStarted),
+		SpansFinished: atomic.LoadUint64(&c.spansFinished),
+		TracesFlushed: atomic.LoadUint64(&c.tracesFlushed),
+		TracesDropped: DroppedTraces{
+			ChannelFull: atomic.LoadUint64(&c.droppedChanFull),
+			BufferFull:  atomic.LoadUint64(&c.droppedBufferFull),
+			Sampler:     atomic.LoadUint64(&c.droppedSampler),
+			Transport:   atomic.LoadUint64(&c.droppedTransport),
+			Shutdown:    atomic.LoadUint64(&c.droppedShutdown),
+		},
+		TruncatedValues: atomic.LoadUint64(&c.truncatedValues),
+		DroppedTags:     atomic.LoadUint64(&c.droppedTags),
+		QueueDepth:      len(t.channels.trace),
+	}
+	if last, ok := t.lastFlush.Load().(flushResult); ok {
+		stats.LastFlush = last.time
+		stats.LastFlushError = last.err
+	}
+	stats.APIVersion, _ = t.apiVersion.Load().(string)
+	return stats
+}
+
+// recordFlush records the outcome of an attempt to send traces. It must
+// only be called by the worker, as it queries the transport.
+func (t *Tracer) recordFlush(err error) {
+	result := flushResult{time: t.Clock().Now()}
+	if err != nil {
+		result.err = err.Error()
+	}
+	t.lastFlush.Store(result)
+	if v, ok := t.transport.(apiVersioner); ok {
+		t.apiVersion.Store(v.APIVersion())
+	}
+}
+
+func init() {
+	expvar.Publish("datadog.tracer", expvar.Func(func() interface{} {
+		return DefaultTracer.Stats()
+	}))
+}
@@ -0,0 +1,101 @@
+package tracer
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingTransport is a Transport failing to send traces.
+type failingTransport struct {
+	*dummyTransport
+}
+
+func (t *failingTransport) SendTraces(traces [][]*Span) (*http.Response, error) {
+	return nil, errors.New("agent unreachable")
+}
+
+func TestTracerStats(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	stats := tracer.Stats()
+	assert.Equal(uint64(0), stats.SpansStarted)
+	assert.True(stats.LastFlush.IsZero())
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	stats = tracer.Stats()
+	assert.Equal(uint64(2), stats.SpansStarted)
+	assert.Equal(uint64(0), stats.SpansFinished)
+
+	child.Finish()
+	root.Finish()
+	assert.Equal(1, tracer.Stats().QueueDepth)
+
+	tracer.ForceFlush()
+	stats = tracer.Stats()
+	assert.Equal(uint64(2), stats.SpansFinished)
+	assert.Equal(uint64(1), stats.TracesFlushed)
+	assert.Equal(DroppedTraces{}, stats.TracesDropped)
+	assert.Equal(0, stats.QueueDepth)
+	assert.False(stats.LastFlush.IsZero())
+	assert.Equal("", stats.LastFlushError)
+}
+
+func TestTracerStatsDropped(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracerTransport(&failingTransport{&dummyTransport{getEncoder: msgpackEncoderFactory}})
+	defer tracer.Stop()
+
+	tracer.SetSampleRate(0)
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	tracer.SetSampleRate(1)
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	tracer.ForceFlush()
+
+	stats := tracer.Stats()
+	assert.Equal(uint64(1), stats.TracesDropped.Sampler)
+	assert.Equal(uint64(1), stats.TracesDropped.Transport)
+	assert.Equal(uint64(0), stats.TracesFlushed)
+	assert.Equal("agent unreachable", stats.LastFlushError)
+}
+
+func TestTracerStatsChannels(t *testing.T) {
+	assert := assert.New(t)
+	channels := newTracerChans()
+
+	buffer := newSpanBuffer(channels, 0, 1)
+	buffer.Push(&Span{TraceID: 1})
+	buffer.Push(&Span{TraceID: 1})
+	assert.Equal(uint64(1), channels.counters.droppedBufferFull)
+
+	for i := 0; i < traceChanLen+2; i++ {
+		channels.pushTrace([]*Span{{TraceID: 1}})
+	}
+	assert.Equal(uint64(2), channels.counters.droppedChanFull)
+}
+
+func TestTracerStatsAPIVersion(t *testing.T) {
+	assert := assert.New(t)
+	transport := newHTTPTransport(defaultHostname, defaultPort)
+	assert.Equal("v0.3", transport.APIVersion())
+	transport.apiDowngrade()
+	assert.Equal("v0.2", transport.APIVersion())
+}
+
+func TestTracerStatsExpvar(t *testing.T) {
+	assert := assert.New(t)
+	v := expvar.Get("datadog.tracer")
+	if !assert.NotNil(v) {
+		return
+	}
+	var stats Stats
+	assert.Nil(json.Unmarshal([]byte(v.String()), &stats))
+	assert.Equal(DefaultTracer.Stats().QueueDepth, stats.QueueDepth)
+}
@@ -0,0 +1,394 @@
+package tracer
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// debugRecentTraces is the number of flushed traces kept by the debug recorder.
+	debugRecentTraces = 20
+	// debugRecentDecisions is the number of sampling decisions kept by the debug recorder.
+	debugRecentDecisions = 100
+	// debugRecentErrors is the number of transport errors kept by the debug recorder.
+	debugRecentErrors = 20
+)
+
+// debugRecorder records what is shown by the debug handler, apart from
+// the open traces: the most recent flushed traces, sampling decisions and
+// transport errors. It is only enabled once a debug handler has been
+// created, so that the tracer doesn't pay for it otherwise.
+type debugRecorder struct {
+	mu sync.Mutex
+
+	traces    []debugTrace    // most recent first
+	decisions []debugDecision // most recent first
+	errors    []debugError    // most recent first
+	kept      uint64          // traces sampled
+	rejected  uint64          // traces rejected by the sampler
+}
+
+// debugSpan is a snapshot of a span, with its children.
+type debugSpan struct {
+	Name     string
+	Service  string
+	Resource string
+	SpanID   uint64
+	Start    time.Time
+	Duration time.Duration
+	Error    bool
+	Finished bool
+	Children []*debugSpan `json:",omitempty"`
+}
+
+// debugTrace is a snapshot of a flushed trace.
+type debugTrace struct {
+	TraceID uint64
+	Flushed time.Time
+	Error   string       `json:",omitempty"` // error of the transport, if it failed to send it
+	Spans   []*debugSpan // root spans of the trace
+}
+
+// debugDecision is a sampling decision.
+type debugDecision struct {
+	Time     time.Time
+	TraceID  uint64
+	Name     string
+	Sampled  bool
+	Priority *int     `json:",omitempty"` // sampling priority the decision was inherited from
+	Rate     *float64 `json:",omitempty"` // sample rate applied by the sampler
+}
+
+// debugError is an error returned by the transport.
+type debugError struct {
+	Time  time.Time
+	Error string
+}
+
+// debugOpenTraceInfo describes an open trace in a debug report.
+type debugOpenTraceInfo struct {
+	TraceID    uint64
+	Name       string
+	Age        time.Duration
+	Spans      int
+	Unfinished []*debugSpan
+}
+
+// debugReport is the content of the debug page.
+type debugReport struct {
+	Time      time.Time
+	Stats     Stats
+	Kept      uint64
+	Rejected  uint64
+	Open      []debugOpenTraceInfo
+	Traces    []debugTrace
+	Decisions []debugDecision
+	Errors    []debugError
+}
+
+// debugRecorder returns the debug recorder of the tracer, or nil if no
+// debug handler has been created.
+func (t *Tracer) debugRecorder() *debugRecorder {
+	if t == nil {
+		return nil
+	}
+	rec, _ := t.debug.Load().(*debugRecorder)
+	return rec
+}
+
+// enableDebugRecorder enables the debug recorder of the tracer, and the
+// tracking of the open traces, and returns it.
+func (t *Tracer) enableDebugRecorder() *debugRecorder {
+	t.debugMu.Lock()
+	defer t.debugMu.Unlock()
+	if rec := t.debugRecorder(); rec != nil {
+		return rec
+	}
+	t.trackOpenTraces()
+	rec := &debugRecorder{}
+	t.debug.Store(rec)
+	return rec
+}
+
+// sample records a sampling decision.
+func (rec *debugRecorder) sample(now time.Time, span *Span, inherited bool) {
+	span.RLock()
+	decision := debugDecision{
+		Time:    now,
+		TraceID: span.TraceID,
+		Name:    span.Name,
+		Sampled: span.Sampled,
+	}
+	if inherited {
+		priority := span.GetSamplingPriority()
+		decision.Priority = &priority
+	} else if rate, ok := span.Metrics[sampleRateMetricKey]; ok {
+		decision.Rate = &rate
+	}
+	span.RUnlock()
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if decision.Sampled {
+		rec.kept++
+	} else {
+		rec.rejected++
+	}
+	if len(rec.decisions) == debugRecentDecisions {
+		rec.decisions = rec.decisions[:debugRecentDecisions-1]
+	}
+	rec.decisions = append(rec.decisions, debugDecision{})
+	copy(rec.decisions[1:], rec.decisions)
+	rec.decisions[0] = decision
+}
+
+// flushTraces records traces sent to the transport, err being the error
+// returned by the transport, if any.
+func (rec *debugRecorder) flushTraces(now time.Time, traces [][]*Span, err error) {
+	flushed := make([]debugTrace, 0, len(traces))
+	for _, trace := range traces {
+		if len(trace) == 0 {
+			continue
+		}
+		t := debugTrace{TraceID: trace[0].TraceID, Flushed: now, Spans: debugTree(trace)}
+		if err != nil {
+			t.Error = err.Error()
+		}
+		flushed = append(flushed, t)
+	}
+	if len(flushed) > debugRecentTraces {
+		flushed = flushed[len(flushed)-debugRecentTraces:]
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if n := len(rec.traces) + len(flushed); n > debugRecentTraces {
+		rec.traces = rec.traces[:len(rec.traces)-(n-debugRecentTraces)]
+	}
+	for _, t := range flushed {
+		rec.traces = append(rec.traces, debugTrace{})
+		copy(rec.traces[1:], rec.traces)
+		rec.traces[0] = t
+	}
+}
+
+// transportError records an error returned by the transport.
+func (rec *debugRecorder) transportError(now time.Time, err error) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.errors) == debugRecentErrors {
+		rec.errors = rec.errors[:debugRecentErrors-1]
+	}
+	rec.errors = append(rec.errors, debugError{})
+	copy(rec.errors[1:], rec.errors)
+	rec.errors[0] = debugError{Time: now, Error: err.Error()}
+}
+
+// recordTransportError records an error returned by the transport, if the
+// debug recorder is enabled.
+func (t *Tracer) recordTransportError(err error) {
+	if rec := t.debugRecorder(); rec != nil {
+		rec.transportError(t.Clock().Now(), err)
+	}
+}
+
+// report returns the content of the debug page.
+func (rec *debugRecorder) report(now time.Time) debugReport {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return debugReport{
+		Time:      now,
+		Kept:      rec.kept,
+		Rejected:  rec.rejected,
+		Traces:    append([]debugTrace(nil), rec.traces...),
+		Decisions: append([]debugDecision(nil), rec.decisions...),
+		Errors:    append([]debugError(nil), rec.errors...),
+	}
+}
+
+// debugOpenTraces describes the open traces of the tracer, as of now,
+// from the oldest to the most recent.
+func (t *Tracer) debugOpenTraces(now time.Time) []debugOpenTraceInfo {
+	var infos []debugOpenTraceInfo
+	for _, trace := range t.open.list() {
+		infos = append(infos, debugOpenTraceInfoOf(trace, now))
+	}
+	sort.Sort(debugOpenTracesByAge(infos))
+	return infos
+}
+
+// debugOpenTraceInfoOf describes the given open trace, as of now.
+func debugOpenTraceInfoOf(trace *openTrace, now time.Time) debugOpenTraceInfo {
+	trace.root.RLock()
+	info := debugOpenTraceInfo{
+		TraceID: trace.root.TraceID,
+		Name:    trace.root.Name,
+	}
+	trace.root.RUnlock()
+	info.Age = trace.age(now)
+
+	spans := trace.spans()
+	info.Spans = len(spans)
+	for _, s := range spans {
+		if span := newDebugSpan(s); !span.Finished {
+			info.Unfinished = append(info.Unfinished, span)
+		}
+	}
+	return info
+}
+
+// newDebugSpan returns a snapshot of the given span, without its children.
+func newDebugSpan(s *Span) *debugSpan {
+	s.RLock()
+	defer s.RUnlock()
+	return &debugSpan{
+		Name:     s.Name,
+		Service:  s.Service,
+		Resource: s.Resource,
+		SpanID:   s.SpanID,
+		Start:    time.Unix(0, s.Start),
+		Duration: time.Duration(s.Duration),
+		Error:    s.Error != 0,
+		Finished: s.finished,
+	}
+}
+
+// debugTree returns the snapshots of the root spans of the given trace,
+// their children being attached to them.
+func debugTree(trace []*Span) []*debugSpan {
+	spans := make(map[uint64]*debugSpan, len(trace))
+	for _, s := range trace {
+		spans[s.SpanID] = newDebugSpan(s)
+	}
+	var roots []*debugSpan
+	for _, s := range trace {
+		span := spans[s.SpanID]
+		if parent, ok := spans[s.ParentID]; ok && s.ParentID != s.SpanID {
+			parent.Children = append(parent.Children, span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+	for _, span := range spans {
+		sort.Sort(debugSpansByStart(span.Children))
+	}
+	sort.Sort(debugSpansByStart(roots))
+	return roots
+}
+
+// debugSpansByStart sorts spans by start time.
+type debugSpansByStart []*debugSpan
+
+func (s debugSpansByStart) Len() int           { return len(s) }
+func (s debugSpansByStart) Less(i, j int) bool { return s[i].Start.Before(s[j].Start) }
+func (s debugSpansByStart) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// debugOpenTracesByAge sorts open traces from the oldest to the most recent.
+type debugOpenTracesByAge []debugOpenTraceInfo
+
+func (s debugOpenTracesByAge) Len() int           { return len(s) }
+func (s debugOpenTracesByAge) Less(i, j int) bool { return s[i].Age > s[j].Age }
+func (s debugOpenTracesByAge) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// DebugHandler returns an http.Handler showing the open traces with their
+// unfinished spans, the recently flushed traces, the sampling decisions and
+// the transport errors of the tracer. It can be mounted like net/http/pprof:
+//
+//	http.Handle("/debug/tracer", tracer.DebugHandler())
+//
+// The report is rendered as HTML, or as JSON with the "format=json" query
+// parameter. The tracer only records what it needs for the report once a
+// debug handler has been created.
+func (t *Tracer) DebugHandler() http.Handler {
+	t.enableDebugRecorder()
+	return debugHandler{t}
+}
+
+// DebugHandler returns the debug handler of the DefaultTracer.
+func DebugHandler() http.Handler {
+	return DefaultTracer.DebugHandler()
+}
+
+type debugHandler struct {
+	tracer *Tracer
+}
+
+func (h debugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	now := h.tracer.Clock().Now()
+	report := h.tracer.debugRecorder().report(now)
+	report.Open = h.tracer.debugOpenTraces(now)
+	report.Stats = h.tracer.Stats()
+
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := debugTemplate.Execute(w, report); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Datadog tracer</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+table { border-collapse: collapse; }
+td, th { padding: 2px 8px; text-align: left; }
+ul { list-style: none; padding-left: 16px; margin: 0; }
+.error { color: #c00; }
+.unfinished { color: #888; }
+</style>
+</head>
+<body>
+<h1>Datadog tracer</h1>
+<p>{{.Time}} &middot; <a href="?format=json">JSON</a></p>
+
+<h2>Statistics</h2>
+<table>
+<tr><td>Spans started</td><td>{{.Stats.SpansStarted}}</td></tr>
+<tr><td>Spans finished</td><td>{{.Stats.SpansFinished}}</td></tr>
+<tr><td>Traces flushed</td><td>{{.Stats.TracesFlushed}}</td></tr>
+<tr><td>Traces dropped</td><td>channel full: {{.Stats.TracesDropped.ChannelFull}}, buffer full: {{.Stats.TracesDropped.BufferFull}}, sampler: {{.Stats.TracesDropped.Sampler}}, transport: {{.Stats.TracesDropped.Transport}}</td></tr>
+<tr><td>Queue depth</td><td>{{.Stats.QueueDepth}}</td></tr>
+<tr><td>Last flush</td><td>{{if .Stats.LastFlush.IsZero}}never{{else}}{{.Stats.LastFlush}}{{end}} <span class="error">{{.Stats.LastFlushError}}</span></td></tr>
+<tr><td>API version</td><td>{{.Stats.APIVersion}}</td></tr>
+</table>
+
+<h2>Open traces ({{len .Open}})</h2>
+{{range .Open}}
+<h3>{{.Name}} &middot; trace {{.TraceID}} &middot; {{.Age}} old &middot; {{len .Unfinished}}/{{.Spans}} spans unfinished</h3>
+<ul>{{range .Unfinished}}<li class="unfinished">{{.Name}} ({{.Service}}, {{.Resource}}) started {{.Start}}</li>{{end}}</ul>
+{{else}}<p>None.</p>{{end}}
+
+<h2>Recently flushed traces</h2>
+{{range .Traces}}
+<h3>trace {{.TraceID}} &middot; {{.Flushed}}{{if .Error}} &middot; <span class="error">{{.Error}}</span>{{end}}</h3>
+<ul>{{range .Spans}}{{template "span" .}}{{end}}</ul>
+{{else}}<p>None.</p>{{end}}
+
+<h2>Sampling decisions</h2>
+<p>Kept: {{.Kept}}, rejected: {{.Rejected}}</p>
+<table>
+<tr><th>Time</th><th>Trace</th><th>Name</th><th>Sampled</th><th>Reason</th></tr>
+{{range .Decisions}}<tr><td>{{.Time}}</td><td>{{.TraceID}}</td><td>{{.Name}}</td><td>{{.Sampled}}</td><td>{{if .Priority}}priority {{.Priority}}{{else if .Rate}}rate {{.Rate}}{{else}}default{{end}}</td></tr>
+{{end}}</table>
+
+<h2>Transport errors</h2>
+<table>
+{{range .Errors}}<tr><td>{{.Time}}</td><td class="error">{{.Error}}</td></tr>
+{{else}}<tr><td>None.</td></tr>{{end}}</table>
+</body>
+</html>
+{{define "span"}}<li{{if .Error}} class="error"{{end}}>{{.Name}} ({{.Service}}, {{.Resource}}) {{.Duration}}{{if .Children}}<ul>{{range .Children}}{{template "span" .}}{{end}}</ul>{{end}}</li>{{end}}
+`))
@@ -0,0 +1,165 @@
+package tracer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getDebugReport queries the given debug handler for a JSON report.
+func getDebugReport(t *testing.T, h http.Handler) debugReport {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/debug/tracer?format=json", nil))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var report debugReport
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return report
+}
+
+func TestDebugHandler(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	h := tracer.DebugHandler()
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	tracer.NewChildSpan("redis.command", root).Finish()
+
+	report := getDebugReport(t, h)
+	assert.Equal(uint64(1), report.Kept)
+	assert.Len(report.Decisions, 1)
+	if assert.Len(report.Open, 1) {
+		open := report.Open[0]
+		assert.Equal(root.TraceID, open.TraceID)
+		assert.Equal("pylons.request", open.Name)
+		assert.Equal(3, open.Spans)
+		assert.Len(open.Unfinished, 2)
+	}
+	assert.Len(report.Traces, 0)
+
+	child.Finish()
+	root.Finish()
+	tracer.ForceFlush()
+
+	report = getDebugReport(t, h)
+	assert.Len(report.Open, 0)
+	if assert.Len(report.Traces, 1) {
+		trace := report.Traces[0]
+		assert.Equal(root.TraceID, trace.TraceID)
+		assert.Equal("", trace.Error)
+		if assert.Len(trace.Spans, 1) {
+			assert.Equal("pylons.request", trace.Spans[0].Name)
+			assert.Len(trace.Spans[0].Children, 2)
+		}
+	}
+	assert.Equal(uint64(1), report.Stats.TracesFlushed)
+}
+
+func TestDebugHandlerSampler(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	h := tracer.DebugHandler()
+
+	tracer.SetSampleRate(0)
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+
+	report := getDebugReport(t, h)
+	assert.Equal(uint64(0), report.Kept)
+	assert.Equal(uint64(1), report.Rejected)
+	assert.Len(report.Open, 0)
+	if assert.Len(report.Decisions, 1) {
+		decision := report.Decisions[0]
+		assert.False(decision.Sampled)
+		if assert.NotNil(decision.Rate) {
+			assert.Equal(0.0, *decision.Rate)
+		}
+	}
+}
+
+func TestDebugHandlerTransportErrors(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracerTransport(&failingTransport{&dummyTransport{getEncoder: msgpackEncoderFactory}})
+	defer tracer.Stop()
+	h := tracer.DebugHandler()
+
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	tracer.ForceFlush()
+
+	report := getDebugReport(t, h)
+	if assert.Len(report.Errors, 1) {
+		assert.Equal("agent unreachable", report.Errors[0].Error)
+	}
+	if assert.Len(report.Traces, 1) {
+		assert.Equal("agent unreachable", report.Traces[0].Error)
+	}
+}
+
+func TestDebugHandlerHTML(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	h := tracer.DebugHandler()
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	tracer.NewChildSpan("redis.<command>", root).Finish()
+	root.Finish()
+	tracer.ForceFlush()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/debug/tracer", nil))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.True(strings.HasPrefix(w.Header().Get("Content-Type"), "text/html"))
+	body := w.Body.String()
+	assert.Contains(body, "pylons.request")
+	assert.Contains(body, "redis.&lt;command&gt;")
+}
+
+func TestDebugRecorderBounds(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	rec := tracer.enableDebugRecorder()
+	assert.Equal(rec, tracer.enableDebugRecorder())
+
+	for i := 0; i < debugRecentDecisions+10; i++ {
+		tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	}
+	tracer.ForceFlush()
+
+	report := rec.report(tracer.Clock().Now())
+	assert.Len(report.Decisions, debugRecentDecisions)
+	assert.Len(report.Traces, debugRecentTraces)
+	assert.Equal(uint64(debugRecentDecisions+10), report.Kept)
+	// most recent first
+	assert.True(!report.Decisions[0].Time.Before(report.Decisions[1].Time))
+}
+
+func TestDebugTree(t *testing.T) {
+	assert := assert.New(t)
+	root := &Span{Name: "root", SpanID: 1, TraceID: 1, Start: 1}
+	a := &Span{Name: "a", SpanID: 2, TraceID: 1, ParentID: 1, Start: 3}
+	b := &Span{Name: "b", SpanID: 3, TraceID: 1, ParentID: 1, Start: 2}
+	c := &Span{Name: "c", SpanID: 4, TraceID: 1, ParentID: 3, Start: 4}
+	orphan := &Span{Name: "orphan", SpanID: 5, TraceID: 1, ParentID: 42, Start: 5}
+
+	roots := debugTree([]*Span{c, a, root, b, orphan})
+	if assert.Len(roots, 2) {
+		assert.Equal("root", roots[0].Name)
+		assert.Equal("orphan", roots[1].Name)
+		children := roots[0].Children
+		if assert.Len(children, 2) {
+			assert.Equal("b", children[0].Name)
+			assert.Equal("a", children[1].Name)
+			if assert.Len(children[0].Children, 1) {
+				assert.Equal("c", children[0].Children[0].Name)
+			}
+		}
+	}
+}
@@ -23,6 +23,10 @@ type Encoder interface {
 
 var mh codec.MsgpackHandle
 
+// statsHandle is used to encode trace stats, which contain binary data that
+// must be encoded with the msgpack bin types of the new spec.
+var statsHandle = codec.MsgpackHandle{WriteExt: true}
+
 // msgpackEncoder encodes a list of traces in Msgpack format
 type msgpackEncoder struct {
 	buffer      *bytes.Buffer
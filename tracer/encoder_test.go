@@ -112,3 +112,77 @@ func TestMsgpackEncoding(t *testing.T) {
 		}
 	}
 }
+
+func TestEncodingSpanEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	span := getTestSpan()
+	span.AddEventWithTime("cache miss", 1481215590883401105, map[string]interface{}{"key": "user:42", "retry": 2})
+	span.AddEventWithTime("done", 1481215590883401106, nil)
+	payload := [][]*Span{{span}}
+
+	// JSON
+	jsonEncoder := newJSONEncoder()
+	assert.Nil(jsonEncoder.EncodeTraces(payload))
+	var jsonTraces [][]*Span
+	assert.Nil(json.NewDecoder(jsonEncoder.buffer).Decode(&jsonTraces))
+
+	// msgpack
+	msgpackEncoder := newMsgpackEncoder()
+	assert.Nil(msgpackEncoder.EncodeTraces(payload))
+	var msgpackTraces [][]*Span
+	var mh codec.MsgpackHandle
+	mh.RawToString = true // decode attribute values as strings, not bytes
+	assert.Nil(codec.NewDecoder(msgpackEncoder.buffer, &mh).Decode(&msgpackTraces))
+
+	for _, traces := range [][][]*Span{jsonTraces, msgpackTraces} {
+		events := traces[0][0].Events
+		assert.Len(events, 2)
+		assert.Equal("cache miss", events[0].Name)
+		assert.Equal(int64(1481215590883401105), events[0].TimeUnixNano)
+		assert.Len(events[0].Attributes, 2)
+		assert.Equal("user:42", events[0].Attributes["key"])
+		assert.EqualValues(2, events[0].Attributes["retry"])
+		assert.Equal("done", events[1].Name)
+		assert.Nil(events[1].Attributes)
+	}
+
+	// spans with no events are encoded as before
+	jsonEncoder = newJSONEncoder()
+	assert.Nil(jsonEncoder.EncodeTraces([][]*Span{{getTestSpan()}}))
+	assert.NotContains(jsonEncoder.buffer.String(), "span_events")
+}
+
+func TestEncodingSpanLinks(t *testing.T) {
+	assert := assert.New(t)
+
+	span := getTestSpan()
+	span.AddLink(43, 53, map[string]string{"topic": "orders"})
+	span.AddLink(44, 54, nil)
+	payload := [][]*Span{{span}}
+
+	// JSON
+	jsonEncoder := newJSONEncoder()
+	assert.Nil(jsonEncoder.EncodeTraces(payload))
+	var jsonTraces [][]*Span
+	assert.Nil(json.NewDecoder(jsonEncoder.buffer).Decode(&jsonTraces))
+
+	// msgpack
+	msgpackEncoder := newMsgpackEncoder()
+	assert.Nil(msgpackEncoder.EncodeTraces(payload))
+	var msgpackTraces [][]*Span
+	var mh codec.MsgpackHandle
+	assert.Nil(codec.NewDecoder(msgpackEncoder.buffer, &mh).Decode(&msgpackTraces))
+
+	for _, traces := range [][][]*Span{jsonTraces, msgpackTraces} {
+		assert.Equal([]SpanLink{
+			{TraceID: 43, SpanID: 53, Attributes: map[string]string{"topic": "orders"}},
+			{TraceID: 44, SpanID: 54},
+		}, traces[0][0].Links)
+	}
+
+	// spans with no links are encoded as before
+	jsonEncoder = newJSONEncoder()
+	assert.Nil(jsonEncoder.EncodeTraces([][]*Span{{getTestSpan()}}))
+	assert.NotContains(jsonEncoder.buffer.String(), "span_links")
+}
@@ -1,49 +1,45 @@
 package tracer
 
 import (
-	"log"
 	"strconv"
+	"strings"
 )
 
-const (
-	errorPrefix = "Datadog Tracer Error: "
-)
-
-// errorSpanBufFull is raised when there's no more room in the buffer
-type errorSpanBufFull struct {
+// ErrorSpanBufFull is raised when there's no more room in the buffer
+type ErrorSpanBufFull struct {
 	// Len is the length of the buffer (which is full)
 	Len int
 }
 
 // Error provides a readable error message.
-func (e *errorSpanBufFull) Error() string {
+func (e *ErrorSpanBufFull) Error() string {
 	return "span buffer is full (length: " + strconv.Itoa(e.Len) + ")"
 }
 
-// errorTraceChanFull is raised when there's no more room in the channel
-type errorTraceChanFull struct {
+// ErrorTraceChanFull is raised when there's no more room in the channel
+type ErrorTraceChanFull struct {
 	// Len is the length of the channel (which is full)
 	Len int
 }
 
 // Error provides a readable error message.
-func (e *errorTraceChanFull) Error() string {
+func (e *ErrorTraceChanFull) Error() string {
 	return "trace channel is full (length: " + strconv.Itoa(e.Len) + ")"
 }
 
-// errorServiceChanFull is raised when there's no more room in the channel
-type errorServiceChanFull struct {
+// ErrorServiceChanFull is raised when there's no more room in the channel
+type ErrorServiceChanFull struct {
 	// Len is the length of the channel (which is full)
 	Len int
 }
 
 // Error provides a readable error message.
-func (e *errorServiceChanFull) Error() string {
+func (e *ErrorServiceChanFull) Error() string {
 	return "service channel is full (length: " + strconv.Itoa(e.Len) + ")"
 }
 
-// errorTraceIDMismatch is raised when a trying to put a span in the wrong place.
-type errorTraceIDMismatch struct {
+// ErrorTraceIDMismatch is raised when a trying to put a span in the wrong place.
+type ErrorTraceIDMismatch struct {
 	// Expected is the trace ID we should have.
 	Expected uint64
 	// Actual is the trace ID we have and is wrong.
@@ -51,7 +47,7 @@ type errorTraceIDMismatch struct {
 }
 
 // Error provides a readable error message.
-func (e *errorTraceIDMismatch) Error() string {
+func (e *ErrorTraceIDMismatch) Error() string {
 	return "trace ID mismatch (expected: " +
 		strconv.FormatUint(e.Expected, 16) +
 		" actual: " +
@@ -59,39 +55,73 @@ func (e *errorTraceIDMismatch) Error() string {
 		")"
 }
 
-// errorNoSpanBuf is raised when trying to finish/push a span that has no buffer associated to it.
-type errorNoSpanBuf struct {
+// ErrorNoSpanBuf is raised when trying to finish/push a span that has no buffer associated to it.
+type ErrorNoSpanBuf struct {
 	// SpanName is the name of the span which could not be pushed (hint for the log reader).
 	SpanName string
 }
 
 // Error provides a readable error message.
-func (e *errorNoSpanBuf) Error() string {
+func (e *ErrorNoSpanBuf) Error() string {
 	return "no span buffer (span name: '" + e.SpanName + "')"
 }
 
-// errorFlushLostTraces is raised when trying to finish/push a span that has no buffer associated to it.
-type errorFlushLostTraces struct {
+// ErrorFlushLostTraces is raised when traces could not be sent to the agent.
+type ErrorFlushLostTraces struct {
 	// Nb is the number of traces lost in that flush
 	Nb int
 }
 
 // Error provides a readable error message.
-func (e *errorFlushLostTraces) Error() string {
+func (e *ErrorFlushLostTraces) Error() string {
 	return "unable to flush traces, lost " + strconv.Itoa(e.Nb) + " traces"
 }
 
-// errorFlushLostServices is raised when trying to finish/push a span that has no buffer associated to it.
-type errorFlushLostServices struct {
+// ErrorFlushLostServices is raised when services could not be sent to the agent.
+type ErrorFlushLostServices struct {
 	// Nb is the number of services lost in that flush
 	Nb int
 }
 
 // Error provides a readable error message.
-func (e *errorFlushLostServices) Error() string {
+func (e *ErrorFlushLostServices) Error() string {
 	return "unable to flush services, lost " + strconv.Itoa(e.Nb) + " services"
 }
 
+// ErrorFlushLostStats is raised when the computed trace stats could not be sent to the agent.
+type ErrorFlushLostStats struct {
+	// Nb is the number of stats buckets lost in that flush
+	Nb int
+}
+
+// Error provides a readable error message.
+func (e *ErrorFlushLostStats) Error() string {
+	return "unable to flush stats, lost " + strconv.Itoa(e.Nb) + " buckets"
+}
+
+// ErrorLeakedTrace is raised when a trace has been open for too long, see LeakDetection.
+type ErrorLeakedTrace struct {
+	// Trace describes the leaked trace.
+	Trace LeakedTrace
+}
+
+// Error provides a readable error message.
+func (e *ErrorLeakedTrace) Error() string {
+	names := make([]string, len(e.Trace.Unfinished))
+	for i, span := range e.Trace.Unfinished {
+		names[i] = span.Name
+	}
+	msg := "trace " + strconv.FormatUint(e.Trace.TraceID, 10) +
+		" (" + e.Trace.Name + ") still open after " + e.Trace.Age.String() +
+		", unfinished spans: " + strings.Join(names, ", ")
+	for _, span := range e.Trace.Unfinished {
+		if span.Stack != "" {
+			msg += "\n" + span.Name + " created at:\n" + span.Stack
+		}
+	}
+	return msg
+}
+
 type errorSummary struct {
 	Count   int
 	Example string
@@ -103,20 +133,24 @@ func errorKey(err error) string {
 		return ""
 	}
 	switch err.(type) {
-	case *errorSpanBufFull:
+	case *ErrorSpanBufFull:
 		return "ErrorSpanBufFull"
-	case *errorTraceChanFull:
+	case *ErrorTraceChanFull:
 		return "ErrorTraceChanFull"
-	case *errorServiceChanFull:
+	case *ErrorServiceChanFull:
 		return "ErrorServiceChanFull"
-	case *errorTraceIDMismatch:
+	case *ErrorTraceIDMismatch:
 		return "ErrorTraceIDMismatch"
-	case *errorNoSpanBuf:
+	case *ErrorNoSpanBuf:
 		return "ErrorNoSpanBuf"
-	case *errorFlushLostTraces:
+	case *ErrorFlushLostTraces:
 		return "ErrorFlushLostTraces"
-	case *errorFlushLostServices:
+	case *ErrorFlushLostServices:
 		return "ErrorFlushLostServices"
+	case *ErrorFlushLostStats:
+		return "ErrorFlushLostStats"
+	case *ErrorLeakedTrace:
+		return "ErrorLeakedTrace"
 	}
 	return err.Error() // possibly high cardinality, but this is unexpected
 }
@@ -142,16 +176,16 @@ func aggregateErrors(errChan <-chan error) map[string]errorSummary {
 
 // logErrors logs the errors, preventing log file flooding, when there
 // are many messages, it caps them and shows a quick summary.
-// As of today it only logs using standard golang log package, but
+// As of today it only logs using the given logger, but
 // later we could send those stats to agent [TODO:christian].
-func logErrors(errChan <-chan error) {
+func logErrors(logger Logger, errChan <-chan error) {
 	errs := aggregateErrors(errChan)
 
 	for _, v := range errs {
-		var repeat string
 		if v.Count > 1 {
-			repeat = " (repeated " + strconv.Itoa(v.Count) + " times)"
+			logger.Log(LevelError, v.Example, "repeated", v.Count)
+		} else {
+			logger.Log(LevelError, v.Example)
 		}
-		log.Println(errorPrefix + v.Example + repeat)
 	}
 }
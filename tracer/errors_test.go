@@ -2,7 +2,9 @@ package tracer
 
 import (
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -10,7 +12,7 @@ import (
 func TestErrorSpanBufFull(t *testing.T) {
 	assert := assert.New(t)
 
-	err := &errorSpanBufFull{Len: 42}
+	err := &ErrorSpanBufFull{Len: 42}
 	assert.Equal("span buffer is full (length: 42)", err.Error())
 	assert.Equal("ErrorSpanBufFull", errorKey(err))
 }
@@ -18,7 +20,7 @@ func TestErrorSpanBufFull(t *testing.T) {
 func TestErrorTraceChanFull(t *testing.T) {
 	assert := assert.New(t)
 
-	err := &errorTraceChanFull{Len: 42}
+	err := &ErrorTraceChanFull{Len: 42}
 	assert.Equal("trace channel is full (length: 42)", err.Error())
 	assert.Equal("ErrorTraceChanFull", errorKey(err))
 }
@@ -26,7 +28,7 @@ func TestErrorTraceChanFull(t *testing.T) {
 func TestErrorServiceChanFull(t *testing.T) {
 	assert := assert.New(t)
 
-	err := &errorServiceChanFull{Len: 42}
+	err := &ErrorServiceChanFull{Len: 42}
 	assert.Equal("service channel is full (length: 42)", err.Error())
 	assert.Equal("ErrorServiceChanFull", errorKey(err))
 }
@@ -34,7 +36,7 @@ func TestErrorServiceChanFull(t *testing.T) {
 func TestErrorTraceIDMismatch(t *testing.T) {
 	assert := assert.New(t)
 
-	err := &errorTraceIDMismatch{Expected: 42, Actual: 65535}
+	err := &ErrorTraceIDMismatch{Expected: 42, Actual: 65535}
 	assert.Equal("trace ID mismatch (expected: 2a actual: ffff)", err.Error())
 	assert.Equal("ErrorTraceIDMismatch", errorKey(err))
 }
@@ -42,24 +44,40 @@ func TestErrorTraceIDMismatch(t *testing.T) {
 func TestErrorNoSpanBuf(t *testing.T) {
 	assert := assert.New(t)
 
-	err := &errorNoSpanBuf{SpanName: "do"}
+	err := &ErrorNoSpanBuf{SpanName: "do"}
 	assert.Equal("no span buffer (span name: 'do')", err.Error())
 }
 
 func TestErrorFlushLostTraces(t *testing.T) {
 	assert := assert.New(t)
 
-	err := &errorFlushLostTraces{Nb: 100}
+	err := &ErrorFlushLostTraces{Nb: 100}
 	assert.Equal("unable to flush traces, lost 100 traces", err.Error())
 }
 
 func TestErrorFlushLostServices(t *testing.T) {
 	assert := assert.New(t)
 
-	err := &errorFlushLostServices{Nb: 100}
+	err := &ErrorFlushLostServices{Nb: 100}
 	assert.Equal("unable to flush services, lost 100 services", err.Error())
 }
 
+func TestErrorLeakedTrace(t *testing.T) {
+	assert := assert.New(t)
+
+	err := &ErrorLeakedTrace{Trace: LeakedTrace{
+		TraceID: 42,
+		Name:    "pylons.request",
+		Age:     time.Minute,
+		Unfinished: []LeakedSpan{
+			{Name: "redis.command"},
+			{Name: "sql.query", Stack: "main.main\n\tmain.go:12\n"},
+		},
+	}}
+	assert.Equal("trace 42 (pylons.request) still open after 1m0s, unfinished spans: redis.command, sql.query\n"+
+		"sql.query created at:\nmain.main\n\tmain.go:12\n", err.Error())
+}
+
 func TestErrorKey(t *testing.T) {
 	assert := assert.New(t)
 
@@ -71,13 +89,13 @@ func TestAggregateErrors(t *testing.T) {
 	assert := assert.New(t)
 
 	errChan := make(chan error, 100)
-	errChan <- &errorSpanBufFull{Len: 1000}
-	errChan <- &errorSpanBufFull{Len: 1000}
-	errChan <- &errorSpanBufFull{Len: 1000}
-	errChan <- &errorSpanBufFull{Len: 1000}
-	errChan <- &errorFlushLostTraces{Nb: 42}
-	errChan <- &errorTraceIDMismatch{Expected: 42, Actual: 1}
-	errChan <- &errorTraceIDMismatch{Expected: 42, Actual: 4095}
+	errChan <- &ErrorSpanBufFull{Len: 1000}
+	errChan <- &ErrorSpanBufFull{Len: 1000}
+	errChan <- &ErrorSpanBufFull{Len: 1000}
+	errChan <- &ErrorSpanBufFull{Len: 1000}
+	errChan <- &ErrorFlushLostTraces{Nb: 42}
+	errChan <- &ErrorTraceIDMismatch{Expected: 42, Actual: 1}
+	errChan <- &ErrorTraceIDMismatch{Expected: 42, Actual: 4095}
 
 	errs := aggregateErrors(errChan)
 
@@ -96,3 +114,59 @@ func TestAggregateErrors(t *testing.T) {
 		},
 	}, errs)
 }
+
+func TestTracerOnError(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracerTransport(&failingTransport{&dummyTransport{getEncoder: msgpackEncoderFactory}})
+	defer tracer.Stop()
+
+	var mu sync.Mutex
+	var errs []error
+	tracer.OnError(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	})
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	root.buffer.maxSize = 1
+	tracer.NewChildSpan("redis.command", root).Finish()
+	root.Finish()
+	tracer.ForceFlush()
+
+	mu.Lock()
+	if assert.Len(errs, 3) {
+		assert.Equal(&ErrorSpanBufFull{Len: 1}, errs[0])
+		assert.Equal("agent unreachable", errs[1].Error())
+		assert.Equal(&ErrorFlushLostTraces{Nb: 1}, errs[2])
+	}
+	errs = nil
+	mu.Unlock()
+
+	tracer.OnError(nil)
+	tracer.channels.pushErr(&ErrorNoSpanBuf{SpanName: "do"})
+	mu.Lock()
+	assert.Len(errs, 0)
+	mu.Unlock()
+}
+
+func TestTracerOnErrorReentrant(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	// the hook can use the trace of the span dropped
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	root.buffer.maxSize = 1
+	var spans []int
+	tracer.OnError(func(err error) {
+		spans = append(spans, root.buffer.Len())
+		tracer.NewRootSpan("tracer.error", "tracer", err.Error()).Finish()
+	})
+	tracer.NewChildSpan("redis.command", root).Finish()
+	root.Finish()
+	tracer.ForceFlush()
+
+	assert.Equal([]int{1}, spans)
+	assert.Len(transport.Traces(), 2)
+}
@@ -0,0 +1,85 @@
+package tracer
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// maxSpanEvents is the maximum number of events kept for a single span.
+	// Events added above that limit are dropped and only counted, so that a
+	// chatty library can't make a span grow without bounds.
+	maxSpanEvents = 100
+	// droppedEventsKey is the metric key holding the number of events that
+	// have been dropped because of maxSpanEvents.
+	droppedEventsKey = "_dd.span_events.dropped"
+)
+
+// SpanEvent is a point-in-time occurrence during the life of a span, such as
+// a cache miss or a retry.
+type SpanEvent struct {
+	Name         string                 `json:"name"`
+	TimeUnixNano int64                  `json:"time_unix_nano"`       // event time expressed in nanoseconds since epoch
+	Attributes   map[string]interface{} `json:"attributes,omitempty"` // strings, booleans and numbers
+}
+
+// AddEvent records an event with the given attributes at the current time.
+// Attribute values other than strings, booleans and numbers are stored
+// as strings. If the Span has been finished, it will not be modified by
+// the method.
+func (s *Span) AddEvent(name string, attrs map[string]interface{}) {
+	if s == nil {
+		return
+	}
+	s.AddEventWithTime(name, s.tracer.Clock().Now().UnixNano(), attrs)
+}
+
+// AddEventWithTime is like AddEvent but records the event at the given
+// time, expressed in nanoseconds since epoch.
+func (s *Span) AddEventWithTime(name string, t int64, attrs map[string]interface{}) {
+	if s == nil {
+		return
+	}
+	event := SpanEvent{Name: name, TimeUnixNano: t}
+	if len(attrs) > 0 {
+		event.Attributes = make(map[string]interface{}, len(attrs))
+		for k, v := range attrs {
+			event.Attributes[k] = eventValue(v)
+		}
+	}
+
+	if !s.lockMutable() {
+		return
+	}
+	defer s.Unlock()
+	if len(s.Events) >= maxSpanEvents {
+		if s.Metrics == nil {
+			s.Metrics = make(map[string]float64)
+		}
+		s.Metrics[droppedEventsKey]++
+		return
+	}
+	s.Events = append(s.Events, event)
+}
+
+// eventValue converts an attribute value into a value that can be encoded
+// by both the msgpack and the JSON encoders.
+func eventValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string, bool,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64:
+		return v
+	case float32:
+		return eventValue(float64(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			// not representable in JSON
+			return fmt.Sprint(v)
+		}
+		return v
+	case error:
+		return v.Error()
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,75 @@
+package tracer
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanAddEvent(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+
+	before := time.Now().UnixNano()
+	attrs := map[string]interface{}{"key": "user:42", "retry": 2}
+	span.AddEvent("cache miss", attrs)
+	attrs["retry"] = 3 // attributes are copied
+	span.AddEventWithTime("done", 1481215590883401105, nil)
+
+	assert.Len(span.Events, 2)
+	assert.Equal("cache miss", span.Events[0].Name)
+	assert.True(span.Events[0].TimeUnixNano >= before)
+	assert.Equal(map[string]interface{}{"key": "user:42", "retry": 2}, span.Events[0].Attributes)
+	assert.Equal("done", span.Events[1].Name)
+	assert.Equal(int64(1481215590883401105), span.Events[1].TimeUnixNano)
+	assert.Nil(span.Events[1].Attributes)
+
+	// operating on a finished span is a no-op
+	span.Finish()
+	span.AddEvent("late", nil)
+	assert.Len(span.Events, 2)
+}
+
+func TestSpanAddEventValues(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+
+	span.AddEvent("values", map[string]interface{}{
+		"bool":   true,
+		"uint":   uint8(1),
+		"float":  float32(0.5),
+		"nan":    math.NaN(),
+		"err":    errors.New("boom"),
+		"struct": struct{ N int }{5},
+	})
+	assert.Equal(map[string]interface{}{
+		"bool":   true,
+		"uint":   uint8(1),
+		"float":  0.5,
+		"nan":    "NaN",
+		"err":    "boom",
+		"struct": "{5}",
+	}, span.Events[0].Attributes)
+}
+
+func TestSpanAddEventLimit(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+
+	for i := 0; i < maxSpanEvents+10; i++ {
+		span.AddEvent("retry", map[string]interface{}{"i": i})
+	}
+	assert.Len(span.Events, maxSpanEvents)
+	assert.Equal(float64(10), span.Metrics["_dd.span_events.dropped"])
+}
+
+func TestEmptySpanAddEvent(t *testing.T) {
+	var span *Span
+	span.AddEvent("event", nil)
+}
@@ -5,4 +5,8 @@ const (
 	ErrorMsg   = "error.msg"
 	ErrorType  = "error.type"
 	ErrorStack = "error.stack"
+
+	// Type and message of the root cause of wrapped errors.
+	ErrorCauseMsg  = "error.cause.msg"
+	ErrorCauseType = "error.cause.type"
 )
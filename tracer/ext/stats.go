@@ -0,0 +1,5 @@
+package ext
+
+// Measured is the metric marking a span which is not top-level as measured:
+// when set to 1, trace stats are computed for it as well.
+const Measured = "_dd.measured"
@@ -4,4 +4,8 @@ package ext
 const (
 	// The pid of the traced process
 	Pid = "system.pid"
+	// The environment of the traced process (e.g. "prod", "staging")
+	Environment = "env"
+	// The version of the traced application
+	Version = "version"
 )
@@ -0,0 +1,14 @@
+package ext
+
+// Tags changing the properties of a span rather than its metadata when set
+// with Span.SetTag.
+const (
+	ServiceName      = "service.name"
+	ResourceName     = "resource.name"
+	SpanType         = "span.type"
+	SamplingPriority = "sampling.priority"
+
+	// Error flags the span as failed if its value is an error or true,
+	// and clears its error status if it's false.
+	Error = "error"
+)
@@ -0,0 +1,10 @@
+package ext
+
+// Tags identifying the user of a trace, see tracer.SetUser.
+const (
+	UserID        = "usr.id"
+	UserEmail     = "usr.email"
+	UserName      = "usr.name"
+	UserRole      = "usr.role"
+	UserSessionID = "usr.session_id"
+)
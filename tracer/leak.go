@@ -0,0 +1,187 @@
+package tracer
+
+import (
+	"time"
+)
+
+// unfinishedKey is the metric set on the spans which were not finished
+// when their trace was flushed by the leak detector.
+const unfinishedKey = "_dd.unfinished"
+
+// LeakDetection configures the detection of the traces which are never
+// finished, typically because Finish is not called on one of their spans.
+// Such traces are never flushed, and are kept in memory until the process
+// exits.
+type LeakDetection struct {
+	// MaxAge is the age after which an open trace is reported as leaked.
+	// Zero disables the detection. Traces are checked whenever the tracer
+	// flushes, which is every couple of seconds.
+	MaxAge time.Duration
+	// CaptureStacks records the stack where each span is created, to
+	// report it when the span is leaked. It is costly, so it should only
+	// be enabled while looking for a leak.
+	CaptureStacks bool
+	// Flush makes the leaked traces flushed, their unfinished spans being
+	// tagged with the _dd.unfinished metric. The spans of a flushed trace
+	// which are finished later, or created later, are dropped.
+	Flush bool
+	// OnLeak is called with every leaked trace. If nil, the leaked traces
+	// are logged.
+	OnLeak func(LeakedTrace)
+}
+
+// LeakedTrace describes a trace reported by the leak detector.
+type LeakedTrace struct {
+	TraceID    uint64
+	Name       string        // name of the root span
+	Age        time.Duration // time since the root span started
+	Spans      int           // number of spans in the trace
+	Unfinished []LeakedSpan
+}
+
+// LeakedSpan describes an unfinished span of a leaked trace.
+type LeakedSpan struct {
+	Name     string
+	Service  string
+	Resource string
+	SpanID   uint64
+	Stack    string // where the span was created, if LeakDetection.CaptureStacks is set
+}
+
+// SetLeakDetection configures the detection of the traces which are never
+// finished.
+func (t *Tracer) SetLeakDetection(cfg LeakDetection) {
+	if cfg.MaxAge > 0 {
+		t.trackOpenTraces()
+	}
+	t.leakDetection.Store(cfg)
+}
+
+// LeakDetection returns the configuration of the detection of the traces
+// which are never finished.
+func (t *Tracer) LeakDetection() LeakDetection {
+	if t == nil { // Defensive, span could be initialized with nil tracer
+		return LeakDetection{}
+	}
+	cfg, _ := t.leakDetection.Load().(LeakDetection)
+	return cfg
+}
+
+// detectLeaks reports the traces which have been open for too long, and
+// flushes them if configured to do so.
+func (t *Tracer) detectLeaks() {
+	cfg := t.LeakDetection()
+	if cfg.MaxAge <= 0 {
+		return
+	}
+	now := t.Clock().Now()
+	for _, trace := range t.open.leaked(now, cfg.MaxAge) {
+		leak := newLeakedTrace(trace, now)
+		if cfg.OnLeak != nil {
+			cfg.OnLeak(leak)
+		} else {
+			t.channels.pushErr(&ErrorLeakedTrace{Trace: leak})
+		}
+		if cfg.Flush {
+			t.flushLeaked(trace, now)
+		}
+	}
+}
+
+// newLeakedTrace describes the given leaked trace, as of now.
+func newLeakedTrace(trace *openTrace, now time.Time) LeakedTrace {
+	trace.root.RLock()
+	leak := LeakedTrace{
+		TraceID: trace.root.TraceID,
+		Name:    trace.root.Name,
+	}
+	trace.root.RUnlock()
+	leak.Age = trace.age(now)
+
+	spans := trace.spans()
+	leak.Spans = len(spans)
+	for _, s := range spans {
+		s.RLock()
+		if !s.finished {
+			leak.Unfinished = append(leak.Unfinished, LeakedSpan{
+				Name:     s.Name,
+				Service:  s.Service,
+				Resource: s.Resource,
+				SpanID:   s.SpanID,
+				Stack:    formatCallerStack(s.stack),
+			})
+		}
+		s.RUnlock()
+	}
+	return leak
+}
+
+// flushLeaked flushes the given leaked trace, the unfinished spans being
+// replaced with finished copies tagged as unfinished.
+func (t *Tracer) flushLeaked(trace *openTrace, now time.Time) {
+	t.open.remove(trace)
+
+	buffer := trace.root.buffer
+	buffer.Lock()
+	spans := buffer.spans
+	priority, hasPriority := buffer.priority, buffer.hasPriority
+	buffer.spans = nil
+	buffer.finishedSpans = 0
+	buffer.abandoned = true
+	buffer.Unlock()
+
+	if len(spans) == 0 || !sampleTrace(spans, priority, hasPriority) {
+		return
+	}
+
+	flushed := make([]*Span, 0, len(spans))
+	for _, s := range spans {
+		s.RLock()
+		finished := s.finished
+		s.RUnlock()
+		if !finished {
+			s = s.unfinishedCopy(now)
+		}
+		flushed = append(flushed, s)
+	}
+	t.channels.pushTrace(flushed)
+}
+
+// unfinishedCopy returns a finished copy of the unfinished span, as of
+// now, tagged as unfinished. The copy can be flushed while the span is
+// still being used.
+func (s *Span) unfinishedCopy(now time.Time) *Span {
+	s.RLock()
+	c := &Span{
+		Name:       s.Name,
+		Service:    s.Service,
+		Resource:   s.Resource,
+		Type:       s.Type,
+		Start:      s.Start,
+		Duration:   s.duration(now),
+		Meta:       make(map[string]string, len(s.Meta)),
+		Metrics:    make(map[string]float64, len(s.Metrics)+1),
+		Events:     append([]SpanEvent(nil), s.Events...),
+		Links:      append([]SpanLink(nil), s.Links...),
+		SpanID:     s.SpanID,
+		TraceID:    s.TraceID,
+		ParentID:   s.ParentID,
+		Error:      s.Error,
+		Sampled:    s.Sampled,
+		tracer:     s.tracer,
+		finished:   true,
+		globalMeta: s.globalMeta,
+	}
+	for k, v := range s.Meta {
+		c.Meta[k] = v
+	}
+	for k, v := range s.Metrics {
+		c.Metrics[k] = v
+	}
+	s.RUnlock()
+
+	c.Metrics[unfinishedKey] = 1
+	truncated, dropped := c.applyLimits(c.tracer.SpanLimits())
+	c.tracer.countLimits(truncated, dropped)
+	return c
+}
@@ -0,0 +1,135 @@
+package tracer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeakDetection(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	clock := &fakeClock{now: time.Unix(1500000000, 0)}
+	tracer.SetClock(clock)
+
+	var leaks []LeakedTrace
+	tracer.SetLeakDetection(LeakDetection{
+		MaxAge: time.Minute,
+		OnLeak: func(leak LeakedTrace) { leaks = append(leaks, leak) },
+	})
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	tracer.NewChildSpan("sql.query", root).Finish()
+	root.Finish()
+
+	clock.Advance(time.Minute)
+	tracer.ForceFlush()
+	assert.Len(leaks, 0)
+
+	clock.Advance(time.Second)
+	tracer.ForceFlush()
+	if assert.Len(leaks, 1) {
+		leak := leaks[0]
+		assert.Equal(root.TraceID, leak.TraceID)
+		assert.Equal("pylons.request", leak.Name)
+		assert.Equal(time.Minute+time.Second, leak.Age)
+		assert.Equal(3, leak.Spans)
+		if assert.Len(leak.Unfinished, 1) {
+			assert.Equal("redis.command", leak.Unfinished[0].Name)
+			assert.Equal(child.SpanID, leak.Unfinished[0].SpanID)
+			assert.Equal("", leak.Unfinished[0].Stack)
+		}
+	}
+
+	// reported once, and not flushed
+	clock.Advance(time.Minute)
+	tracer.ForceFlush()
+	assert.Len(leaks, 1)
+	assert.Len(transport.Traces(), 0)
+
+	// the trace is flushed as usual once finished
+	child.Finish()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 1)
+	assert.Len(tracer.open.list(), 0)
+}
+
+func TestLeakDetectionFlush(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	clock := &fakeClock{now: time.Unix(1500000000, 0)}
+	tracer.SetClock(clock)
+	tracer.SetLeakDetection(LeakDetection{
+		MaxAge: time.Minute,
+		Flush:  true,
+		OnLeak: func(LeakedTrace) {},
+	})
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	child.SetMeta("redis.raw_command", "GET key")
+	root.Finish()
+
+	clock.Advance(2 * time.Minute)
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	if assert.Len(traces, 1) && assert.Len(traces[0], 2) {
+		flushedRoot, flushedChild := traces[0][0], traces[0][1]
+		assert.Equal(root, flushedRoot)
+		assert.NotContains(flushedRoot.Metrics, unfinishedKey)
+
+		assert.True(flushedChild != child)
+		assert.Equal(child.SpanID, flushedChild.SpanID)
+		assert.Equal("GET key", flushedChild.Meta["redis.raw_command"])
+		assert.Equal(1.0, flushedChild.Metrics[unfinishedKey])
+		assert.Equal(int64(2*time.Minute), flushedChild.Duration)
+	}
+	assert.Len(tracer.open.list(), 0)
+
+	// the spans of the flushed trace are dropped
+	tracer.NewChildSpan("sql.query", root).Finish()
+	child.Finish()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+}
+
+func TestLeakDetectionStacks(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	clock := &fakeClock{now: time.Unix(1500000000, 0)}
+	tracer.SetClock(clock)
+	tracer.SetLeakDetection(LeakDetection{MaxAge: time.Minute, CaptureStacks: true})
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	defer root.Finish()
+	clock.Advance(2 * time.Minute)
+	tracer.detectLeaks()
+
+	select {
+	case err := <-tracer.channels.err:
+		leak, ok := err.(*ErrorLeakedTrace)
+		if assert.True(ok) && assert.Len(leak.Trace.Unfinished, 1) {
+			stack := leak.Trace.Unfinished[0].Stack
+			assert.True(strings.HasPrefix(stack, "github.com/DataDog/dd-trace-go/tracer.TestLeakDetectionStacks\n"), stack)
+		}
+	default:
+		t.Fatal("leaked trace not reported")
+	}
+}
+
+func TestLeakDetectionDisabled(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	assert.Equal(LeakDetection{}, tracer.LeakDetection())
+	tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.False(tracer.tracksOpenTraces())
+	assert.Len(tracer.open.list(), 0)
+}
@@ -0,0 +1,146 @@
+package tracer
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+const (
+	// default limits, matching the ones enforced by the agent
+	defaultMaxMetaValueLen = 25000
+	defaultMaxResourceLen  = 5000
+	defaultMaxTags         = 1000
+
+	// truncationSuffix is appended to the values that have been truncated.
+	truncationSuffix = "..."
+	// droppedTagsKey is the metric key holding the number of tags that have
+	// been dropped because of the SpanLimits.
+	droppedTagsKey = "_dd.tags.dropped"
+)
+
+// SpanLimits limits the size of the spans, so that a single span can't
+// produce a payload rejected by the agent. Limits are enforced when spans
+// are finished: longer values are truncated and marked with a "..." suffix,
+// extra tags are dropped. For all the fields, zero means the default limit
+// and a negative value means no limit.
+type SpanLimits struct {
+	// MaxMetaValueLen is the maximum length of a meta value, in bytes.
+	// Defaults to 25000.
+	MaxMetaValueLen int
+	// MaxResourceLen is the maximum length of the resource, in bytes.
+	// Defaults to 5000.
+	MaxResourceLen int
+	// MaxTags is the maximum number of meta and of metrics of a span.
+	// Internal tags, prefixed with "_", and the tags set by the tracer,
+	// such as the error ones, are not limited.
+	// Defaults to 1000.
+	MaxTags int
+}
+
+func limit(value, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// applyLimits enforces the limits on the span, returning the number of
+// values that have been truncated and of tags that have been dropped. The
+// span must be locked.
+func (s *Span) applyLimits(l SpanLimits) (truncated, dropped int) {
+	maxValueLen := limit(l.MaxMetaValueLen, defaultMaxMetaValueLen)
+	if v, ok := truncate(s.Resource, limit(l.MaxResourceLen, defaultMaxResourceLen)); ok {
+		s.Resource = v
+		truncated++
+	}
+	for k, v := range s.Meta {
+		if v, ok := truncate(v, maxValueLen); ok {
+			s.Meta[k] = v
+			truncated++
+		}
+	}
+
+	if maxTags := limit(l.MaxTags, defaultMaxTags); maxTags > 0 {
+		for _, k := range extraTags(stringKeys(s.Meta), maxTags) {
+			delete(s.Meta, k)
+			dropped++
+		}
+		for _, k := range extraTags(floatKeys(s.Metrics), maxTags) {
+			delete(s.Metrics, k)
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		if s.Metrics == nil {
+			s.Metrics = make(map[string]float64)
+		}
+		s.Metrics[droppedTagsKey] += float64(dropped)
+	}
+	return truncated, dropped
+}
+
+// truncate returns v truncated to max bytes, suffix included, without
+// splitting UTF-8 characters. Limits too small for the suffix truncate the
+// value without it.
+func truncate(v string, max int) (string, bool) {
+	if max < 0 || len(v) <= max {
+		return v, false
+	}
+	suffix := truncationSuffix
+	if max < len(suffix) {
+		suffix = ""
+	}
+	n := max - len(suffix)
+	for n > 0 && !utf8.RuneStart(v[n]) {
+		n--
+	}
+	return v[:n] + suffix, true
+}
+
+// extraTags returns the keys above the limit, reserved keys excepted. Keys
+// are sorted so that the same tags are always dropped.
+func extraTags(keys []string, max int) []string {
+	if len(keys) <= max {
+		return nil
+	}
+	user := keys[:0]
+	for _, k := range keys {
+		if !reservedTag(k) {
+			user = append(user, k)
+		}
+	}
+	if len(user) <= max {
+		return nil
+	}
+	sort.Strings(user)
+	return user[max:]
+}
+
+// reservedTag returns true if the tag is set by the tracer itself, in which
+// case it's never dropped.
+func reservedTag(key string) bool {
+	switch key {
+	case ext.Pid, errorMsgKey, errorTypeKey, errorStackKey, errorCauseMsgKey, errorCauseTypeKey:
+		return true
+	}
+	return strings.HasPrefix(key, "_")
+}
+
+func stringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func floatKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
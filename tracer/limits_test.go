@@ -0,0 +1,85 @@
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanLimitsDefault(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+	span := tracer.NewRootSpan("elasticsearch.query", "elasticsearch", strings.Repeat("r", 6000))
+	span.SetMeta("elasticsearch.body", strings.Repeat("b", 30000))
+	span.SetMeta("short", "value")
+	span.Finish()
+
+	assert.Len(span.Resource, defaultMaxResourceLen)
+	assert.True(strings.HasSuffix(span.Resource, "..."))
+	assert.Len(span.Meta["elasticsearch.body"], defaultMaxMetaValueLen)
+	assert.True(strings.HasSuffix(span.Meta["elasticsearch.body"], "..."))
+	assert.Equal("value", span.Meta["short"])
+	assert.Equal(uint64(2), atomic.LoadUint64(&tracer.channels.counters.truncatedValues))
+	assert.Equal(uint64(0), atomic.LoadUint64(&tracer.channels.counters.droppedTags))
+}
+
+func TestSpanLimitsConfig(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+	tracer.SetSpanLimits(SpanLimits{MaxMetaValueLen: 8, MaxResourceLen: -1, MaxTags: 2})
+	assert.Equal(SpanLimits{MaxMetaValueLen: 8, MaxResourceLen: -1, MaxTags: 2}, tracer.SpanLimits())
+
+	span := tracer.NewRootSpan("sql.query", "db", strings.Repeat("r", 6000))
+	span.SetMeta("a", "select * from users")
+	span.SetMeta("b", "héhéhé") // multi-byte characters are not split
+	span.SetMeta("c", "dropped")
+	span.SetMetric("x", 1)
+	span.SetMetric("y", 2)
+	span.SetMetric("z", 3)
+	span.SetSamplingPriority(1)
+	span.SetError(errors.New("boom"))
+	span.Finish()
+
+	assert.Len(span.Resource, 6000)
+	assert.Equal("selec...", span.Meta["a"])
+	assert.Equal("héh...", span.Meta["b"])
+	_, ok := span.Meta["c"]
+	assert.False(ok)
+	assert.Equal(float64(1), span.Metrics["x"])
+	assert.Equal(float64(2), span.Metrics["y"])
+	_, ok = span.Metrics["z"]
+	assert.False(ok)
+	// reserved tags are never dropped
+	assert.Equal(1, span.GetSamplingPriority())
+	assert.Equal("boom", span.Meta["error.msg"])
+	_, ok = span.Meta["system.pid"]
+	assert.True(ok)
+	assert.Equal(float64(2), span.Metrics["_dd.tags.dropped"])
+	assert.Equal(uint64(2), atomic.LoadUint64(&tracer.channels.counters.droppedTags))
+}
+
+func TestTruncate(t *testing.T) {
+	assert := assert.New(t)
+	for _, tt := range []struct {
+		in, out string
+		max     int
+	}{
+		{"abcdef", "abcdef", 6},
+		{"abcdef", "ab...", 5},
+		{"abcdefg", "abc...", 6},
+		{"abcdefg", "...", 3},
+		{"abcdefg", "ab", 2},
+		{"abcdefg", "", 0},
+		{"日本語", "", 2},
+		{"abcdefg", "abcdefg", -1},
+		{"日本語", "日...", 8},
+	} {
+		out, truncated := truncate(tt.in, tt.max)
+		assert.Equal(tt.out, out, fmt.Sprintf("truncate(%q, %d)", tt.in, tt.max))
+		assert.Equal(tt.in != tt.out, truncated)
+	}
+}
@@ -0,0 +1,47 @@
+package tracer
+
+const (
+	// maxSpanLinks is the maximum number of links kept for a single span.
+	maxSpanLinks = 100
+	// droppedLinksKey is the metric key holding the number of links that
+	// have been dropped because of maxSpanLinks.
+	droppedLinksKey = "_dd.span_links.dropped"
+)
+
+// SpanLink references a span that is causally related to the current one but
+// that is not its parent, possibly in another trace. For instance, a span
+// processing a batch of messages links the spans that produced them.
+type SpanLink struct {
+	TraceID    uint64            `json:"trace_id"`             // identifier of the trace of the linked span
+	SpanID     uint64            `json:"span_id"`              // identifier of the linked span
+	Attributes map[string]string `json:"attributes,omitempty"` // arbitrary map describing the link
+}
+
+// AddLink links the span identified by the given trace and span ids to the
+// current one. If the Span has been finished, it will not be modified by the
+// method.
+func (s *Span) AddLink(traceID, spanID uint64, attrs map[string]string) {
+	if s == nil {
+		return
+	}
+	link := SpanLink{TraceID: traceID, SpanID: spanID}
+	if len(attrs) > 0 {
+		link.Attributes = make(map[string]string, len(attrs))
+		for k, v := range attrs {
+			link.Attributes[k] = v
+		}
+	}
+
+	if !s.lockMutable() {
+		return
+	}
+	defer s.Unlock()
+	if len(s.Links) >= maxSpanLinks {
+		if s.Metrics == nil {
+			s.Metrics = make(map[string]float64)
+		}
+		s.Metrics[droppedLinksKey]++
+		return
+	}
+	s.Links = append(s.Links, link)
+}
@@ -0,0 +1,42 @@
+package tracer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanAddLink(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+	span := tracer.NewRootSpan("kafka.consume", "consumer", "orders")
+
+	attrs := map[string]string{"topic": "orders"}
+	span.AddLink(42, 52, attrs)
+	attrs["topic"] = "users" // attributes are copied
+	span.AddLink(43, 53, nil)
+	assert.Equal([]SpanLink{
+		{TraceID: 42, SpanID: 52, Attributes: map[string]string{"topic": "orders"}},
+		{TraceID: 43, SpanID: 53},
+	}, span.Links)
+
+	// operating on a finished span is a no-op
+	span.Finish()
+	span.AddLink(44, 54, nil)
+	assert.Len(span.Links, 2)
+
+	var empty *Span
+	empty.AddLink(42, 52, nil)
+}
+
+func TestSpanAddLinkLimit(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+	span := tracer.NewRootSpan("kafka.consume", "consumer", "orders")
+
+	for i := 0; i < maxSpanLinks+10; i++ {
+		span.AddLink(uint64(i+1), uint64(i+1), nil)
+	}
+	assert.Len(span.Links, maxSpanLinks)
+	assert.Equal(float64(10), span.Metrics["_dd.span_links.dropped"])
+}
@@ -0,0 +1,146 @@
+package tracer
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+// LogLevel is the level of a log message.
+type LogLevel int
+
+const (
+	// LevelDebug is the level of the messages only logged in debug mode,
+	// see Tracer.SetDebugLogging.
+	LevelDebug LogLevel = iota
+	// LevelInfo is the level of the informational messages.
+	LevelInfo
+	// LevelWarn is the level of the messages about unexpected but
+	// recoverable situations, such as a fallback being used.
+	LevelWarn
+	// LevelError is the level of the messages about the errors of the
+	// tracer, typically data being lost.
+	LevelError
+)
+
+// String returns the name of the level.
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "Debug"
+	case LevelInfo:
+		return "Info"
+	case LevelWarn:
+		return "Warn"
+	case LevelError:
+		return "Error"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// Logger is the interface through which the tracer writes all its output.
+// It can be implemented to integrate the tracer with structured logging.
+type Logger interface {
+	// Log logs a message with the given level and fields, keyvals being
+	// alternating keys and values, the keys being strings.
+	Log(level LogLevel, msg string, keyvals ...interface{})
+}
+
+// NewStdLogger returns a Logger writing to the given standard logger, or
+// with the standard log package functions if it's nil. Messages are
+// written on a single line, prefixed with the level and followed with the
+// fields as key=value pairs. This is the default logger.
+func NewStdLogger(l *log.Logger) Logger {
+	return stdLogger{l}
+}
+
+type stdLogger struct {
+	logger *log.Logger
+}
+
+// Log implements Logger.
+func (l stdLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	var buf bytes.Buffer
+	buf.WriteString("Datadog Tracer ")
+	buf.WriteString(level.String())
+	buf.WriteString(": ")
+	buf.WriteString(msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		buf.WriteByte(' ')
+		if i+1 < len(keyvals) {
+			fmt.Fprintf(&buf, "%v=%v", keyvals[i], keyvals[i+1])
+		} else {
+			fmt.Fprintf(&buf, "%v=?", keyvals[i])
+		}
+	}
+	if l.logger != nil {
+		l.logger.Output(2, buf.String())
+	} else {
+		log.Output(2, buf.String())
+	}
+}
+
+// NewNopLogger returns a Logger discarding all the messages.
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+// Log implements Logger.
+func (nopLogger) Log(LogLevel, string, ...interface{}) {}
+
+// loggerHolder wraps a Logger, since an atomic.Value must always hold
+// values of the same concrete type.
+type loggerHolder struct{ Logger }
+
+// defaultLogger is the logger of the tracers which have no logger of their
+// own, and of the code which doesn't belong to a tracer.
+var defaultLogger atomic.Value
+
+// SetLogger sets the logger used by default by all the tracers, including
+// the DefaultTracer, and by the code which doesn't belong to a tracer. A nil
+// logger restores the default one, writing with the standard log package.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = NewStdLogger(nil)
+	}
+	defaultLogger.Store(loggerHolder{l})
+}
+
+// packageLogger returns the logger set with SetLogger.
+func packageLogger() Logger {
+	if h, ok := defaultLogger.Load().(loggerHolder); ok {
+		return h.Logger
+	}
+	return stdLogger{}
+}
+
+// loggerSetter is implemented by the transports which log, so that they
+// use the logger of their tracer.
+type loggerSetter interface {
+	setLogger(l Logger)
+}
+
+// SetLogger sets the logger through which the tracer, and its transport,
+// write all their output. A nil logger restores the default one, see the
+// package-level SetLogger function.
+func (t *Tracer) SetLogger(l Logger) {
+	t.logger.Store(loggerHolder{l})
+	if s, ok := t.transport.(loggerSetter); ok {
+		s.setLogger(l)
+	}
+}
+
+// Logger returns the logger through which the tracer writes its output.
+// Integrations can use it to write theirs.
+func (t *Tracer) Logger() Logger {
+	if t == nil { // Defensive, span could be initialized with nil tracer
+		return packageLogger()
+	}
+	if h, ok := t.logger.Load().(loggerHolder); ok && h.Logger != nil {
+		return h.Logger
+	}
+	return packageLogger()
+}
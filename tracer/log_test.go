@@ -0,0 +1,120 @@
+package tracer
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testLogger is a Logger recording the messages.
+type testLogger struct {
+	sync.Mutex
+	lines []string
+}
+
+func (l *testLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	l.lines = append(l.lines, fmt.Sprint(level, " ", msg, " ", keyvals))
+}
+
+func (l *testLogger) Lines() []string {
+	l.Lock()
+	defer l.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+func TestStdLogger(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "", 0))
+
+	logger.Log(LevelError, "span buffer is full", "length", 10, "odd")
+	logger.Log(LevelDebug, "sending traces")
+	assert.Equal("Datadog Tracer Error: span buffer is full length=10 odd=?\n"+
+		"Datadog Tracer Debug: sending traces\n", buf.String())
+	assert.Equal("Level(42)", LogLevel(42).String())
+}
+
+func TestNopLogger(t *testing.T) {
+	NewNopLogger().Log(LevelError, "discarded", "key", "value")
+}
+
+func TestTracerLogger(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	assert.Equal(NewStdLogger(nil), tracer.Logger())
+
+	logger := &testLogger{}
+	tracer.SetLogger(logger)
+	assert.Equal(logger, tracer.Logger())
+
+	tracer.SetSampleRate(2)
+	tracer.channels.pushErr(&ErrorSpanBufFull{Len: 10})
+	tracer.channels.pushErr(&ErrorSpanBufFull{Len: 10})
+	tracer.ForceFlush()
+	assert.Equal([]string{
+		"Warn tracer.SetSampleRate rate must be between 0 and 1 [rate 2]",
+		"Error span buffer is full (length: 10) [repeated 2]",
+	}, logger.Lines())
+
+	tracer.SetLogger(nil)
+	assert.Equal(NewStdLogger(nil), tracer.Logger())
+}
+
+func TestTracerLoggerDebug(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	logger := &testLogger{}
+	tracer.SetLogger(logger)
+	tracer.SetDebugLogging(true)
+
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	tracer.ForceFlush()
+	lines := logger.Lines()
+	if assert.Len(lines, 3) {
+		assert.Equal("Debug sending traces [count 1]", lines[0])
+		assert.Contains(lines[2], "Name: pylons.request")
+	}
+}
+
+func TestPackageLogger(t *testing.T) {
+	assert := assert.New(t)
+	defer SetLogger(nil)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	logger := &testLogger{}
+	SetLogger(logger)
+	assert.Equal(logger, packageLogger())
+	assert.Equal(logger, tracer.Logger())
+
+	// the logger of the tracer takes precedence
+	other := &testLogger{}
+	tracer.SetLogger(other)
+	assert.Equal(other, tracer.Logger())
+
+	SetLogger(nil)
+	assert.Equal(NewStdLogger(nil), packageLogger())
+}
+
+func TestTransportLogger(t *testing.T) {
+	assert := assert.New(t)
+	transport := newHTTPTransport(defaultHostname, defaultPort)
+	tracer := NewTracerTransport(transport)
+	defer tracer.Stop()
+	assert.Equal(packageLogger(), transport.logger())
+
+	logger := &testLogger{}
+	tracer.SetLogger(logger)
+	assert.Equal(logger, transport.logger())
+
+	tracer.SetLogger(nil)
+	assert.Equal(packageLogger(), transport.logger())
+}
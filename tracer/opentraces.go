@@ -0,0 +1,111 @@
+package tracer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// openTraces tracks the traces which are not finished yet. It is only
+// enabled when needed, by the debug handler and the leak detector, so that
+// the tracer doesn't pay for it otherwise.
+type openTraces struct {
+	mu     sync.Mutex
+	traces map[*spanBuffer]*openTrace // open traces, by buffer
+}
+
+// openTrace is a trace which is not finished yet.
+type openTrace struct {
+	root     *Span
+	finished int  // number of finished spans
+	leaked   bool // true once reported by the leak detector
+}
+
+// trackOpenTraces enables the tracking of the open traces. The traces
+// started before are not tracked.
+func (t *Tracer) trackOpenTraces() {
+	t.open.mu.Lock()
+	defer t.open.mu.Unlock()
+	if t.open.traces == nil {
+		t.open.traces = make(map[*spanBuffer]*openTrace)
+	}
+	atomic.StoreUint32(&t.trackOpen, 1)
+}
+
+// tracksOpenTraces returns true if the open traces are tracked.
+func (t *Tracer) tracksOpenTraces() bool {
+	return t != nil && atomic.LoadUint32(&t.trackOpen) == 1
+}
+
+// add records that a trace has been started with the given root span.
+func (o *openTraces) add(root *Span) {
+	o.mu.Lock()
+	o.traces[root.buffer] = &openTrace{root: root}
+	o.mu.Unlock()
+}
+
+// finish records that a span has been finished, closing its trace once
+// all its spans are finished.
+func (o *openTraces) finish(s *Span) {
+	if s.buffer == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	trace, ok := o.traces[s.buffer]
+	if !ok {
+		return
+	}
+	trace.finished++
+	if trace.finished >= s.buffer.Len() {
+		delete(o.traces, s.buffer)
+	}
+}
+
+// remove stops tracking the given trace.
+func (o *openTraces) remove(trace *openTrace) {
+	o.mu.Lock()
+	delete(o.traces, trace.root.buffer)
+	o.mu.Unlock()
+}
+
+// list returns the open traces.
+func (o *openTraces) list() []*openTrace {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	traces := make([]*openTrace, 0, len(o.traces))
+	for _, trace := range o.traces {
+		traces = append(traces, trace)
+	}
+	return traces
+}
+
+// leaked returns the open traces which are older than maxAge and were not
+// reported yet, marking them as reported.
+func (o *openTraces) leaked(now time.Time, maxAge time.Duration) []*openTrace {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var traces []*openTrace
+	for _, trace := range o.traces {
+		if !trace.leaked && trace.age(now) > maxAge {
+			trace.leaked = true
+			traces = append(traces, trace)
+		}
+	}
+	return traces
+}
+
+// age returns the age of the trace, as of now.
+func (trace *openTrace) age(now time.Time) time.Duration {
+	trace.root.RLock()
+	defer trace.root.RUnlock()
+	return time.Duration(trace.root.duration(now))
+}
+
+// spans returns the spans of the trace.
+func (trace *openTrace) spans() []*Span {
+	buffer := trace.root.buffer
+	buffer.RLock()
+	defer buffer.RUnlock()
+	return append([]*Span(nil), buffer.spans...)
+}
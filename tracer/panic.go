@@ -0,0 +1,96 @@
+package tracer
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// panicError is the error recorded in a span when the traced code panics.
+type panicError struct {
+	value interface{} // value passed to panic
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// Unwrap returns the panic value if it is an error, so that its type and
+// message are recorded as the cause of the panic.
+func (e *panicError) Unwrap() error {
+	err, _ := e.value.(error)
+	return err
+}
+
+// SetPanicRecovery sets whether panics recovered by Trace and Go are
+// swallowed once recorded in their span. It is disabled by default: panics
+// are propagated after the span has been finished.
+func (t *Tracer) SetPanicRecovery(enabled bool) {
+	if enabled {
+		atomic.StoreUint32(&t.recoverPanics, 1)
+	} else {
+		atomic.StoreUint32(&t.recoverPanics, 0)
+	}
+}
+
+// PanicRecoveryEnabled returns whether panics are swallowed by Trace and Go.
+func (t *Tracer) PanicRecoveryEnabled() bool {
+	return atomic.LoadUint32(&t.recoverPanics) == 1
+}
+
+// Trace calls fn with a context holding a new child span of the span
+// contained in ctx. The span is finished when fn returns, with the returned
+// error if any. If fn panics, the panic value and its stack trace are
+// recorded as the span error and the span is finished before the panic is
+// propagated, unless panic recovery is enabled, in which case the panic is
+// returned as an error.
+func (t *Tracer) Trace(ctx context.Context, name string, fn func(context.Context) error) error {
+	span, ctx := t.NewChildSpanWithContext(name, ctx)
+	return t.trace(span, ctx, fn)
+}
+
+// Go calls fn in a new goroutine, with a context holding a new child span of
+// the span contained in ctx. The span is finished when fn returns. Panics are
+// handled as they are by Trace.
+func (t *Tracer) Go(ctx context.Context, name string, fn func(context.Context)) {
+	// create the span right away, so that it starts when the goroutine is
+	// launched, not when it is scheduled
+	span, ctx := t.NewChildSpanWithContext(name, ctx)
+	go t.trace(span, ctx, func(ctx context.Context) error {
+		fn(ctx)
+		return nil
+	})
+}
+
+// trace calls fn and finishes the span, recording the panics of fn.
+func (t *Tracer) trace(span *Span, ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		perr := &panicError{value: r}
+		// skip this function, the stack starts with the call to panic
+		span.setError(perr, 1)
+		span.Finish()
+		if !t.PanicRecoveryEnabled() {
+			panic(r)
+		}
+		err = perr
+	}()
+	err = fn(ctx)
+	span.FinishWithErr(err)
+	return err
+}
+
+// Trace calls fn with a child span of the span contained in ctx, using the
+// DefaultTracer. See Tracer.Trace.
+func Trace(ctx context.Context, name string, fn func(context.Context) error) error {
+	return DefaultTracer.Trace(ctx, name, fn)
+}
+
+// Go calls fn in a new goroutine with a child span of the span contained in
+// ctx, using the DefaultTracer. See Tracer.Go.
+func Go(ctx context.Context, name string, fn func(context.Context)) {
+	DefaultTracer.Go(ctx, name, fn)
+}
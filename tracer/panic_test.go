@@ -0,0 +1,150 @@
+package tracer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrace(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	parent := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	ctx := parent.Context(context.Background())
+
+	var child *Span
+	err := tracer.Trace(ctx, "db.query", func(ctx context.Context) error {
+		child, _ = SpanFromContext(ctx)
+		return errors.New("timeout")
+	})
+	assert.EqualError(err, "timeout")
+	parent.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+	assert.Equal("db.query", child.Name)
+	assert.Equal(parent.SpanID, child.ParentID)
+	assert.True(child.finished)
+	assert.Equal(int32(1), child.Error)
+	assert.Equal("timeout", child.Meta["error.msg"])
+}
+
+func TestTracePanic(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	parent := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	ctx := parent.Context(context.Background())
+
+	var child *Span
+	assert.PanicsWithValue("boom", func() {
+		tracer.Trace(ctx, "db.query", func(ctx context.Context) error {
+			child, _ = SpanFromContext(ctx)
+			panic("boom")
+		})
+	})
+	parent.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+	assert.True(child.finished)
+	assert.Equal(int32(1), child.Error)
+	assert.Equal("panic: boom", child.Meta["error.msg"])
+	assert.Equal("*tracer.panicError", child.Meta["error.type"])
+	assert.Contains(child.Meta["error.stack"], "tracer.TestTracePanic")
+	_, ok := child.Meta["error.cause.msg"]
+	assert.False(ok)
+}
+
+func TestTracePanicRecovery(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetPanicRecovery(true)
+	assert.True(tracer.PanicRecoveryEnabled())
+
+	var child *Span
+	err := tracer.Trace(context.Background(), "db.query", func(ctx context.Context) error {
+		child, _ = SpanFromContext(ctx)
+		panic(errors.New("boom"))
+	})
+	assert.EqualError(err, "panic: boom")
+	assert.True(child.finished)
+	assert.Equal("panic: boom", child.Meta["error.msg"])
+	assert.Equal("boom", child.Meta["error.cause.msg"])
+	assert.Equal("*errors.errorString", child.Meta["error.cause.type"])
+
+	tracer.SetPanicRecovery(false)
+	assert.False(tracer.PanicRecoveryEnabled())
+}
+
+func TestGo(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetPanicRecovery(true)
+
+	parent := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	ctx := parent.Context(context.Background())
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	workers := make([]*Span, 2)
+	tracer.Go(ctx, "worker.ok", func(ctx context.Context) {
+		defer wg.Done()
+		span, ok := SpanFromContext(ctx)
+		assert.True(ok)
+		assert.Equal(parent.SpanID, span.ParentID)
+		workers[0] = span
+	})
+	tracer.Go(ctx, "worker.crash", func(ctx context.Context) {
+		defer wg.Done()
+		workers[1], _ = SpanFromContext(ctx)
+		panic("boom")
+	})
+	wg.Wait()
+
+	// the spans are finished after fn returns, wait for them to be
+	finished := func(span *Span) bool {
+		span.RLock()
+		defer span.RUnlock()
+		return span.finished
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for !(finished(workers[0]) && finished(workers[1])) {
+		if time.Now().After(deadline) {
+			t.Fatal("the worker spans were not finished")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	parent.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	if !assert.Len(traces, 1) {
+		return
+	}
+	trace := traces[0]
+	assert.Len(trace, 3)
+	for _, span := range trace {
+		switch span.Name {
+		case "worker.ok":
+			assert.Equal(int32(0), span.Error)
+		case "worker.crash":
+			assert.Equal(int32(1), span.Error)
+			assert.True(strings.HasPrefix(span.Meta["error.msg"], "panic: boom"))
+		}
+	}
+}
@@ -2,7 +2,7 @@ package tracer
 
 import (
 	cryptorand "crypto/rand"
-	"log"
+	"fmt"
 	"math"
 	"math/big"
 	"math/rand"
@@ -10,41 +10,67 @@ import (
 	"time"
 )
 
-// randGen is the global thread safe random number generator
-var randGen *rand.Rand
+// traceIDHighKey is the meta key holding the upper 64 bits of 128-bit trace
+// ids, hex encoded.
+const traceIDHighKey = "_dd.p.tid"
 
-type randSource struct {
-	source rand.Source
-	sync.Mutex
+// IDGenerator generates the ids of the spans and traces created by a Tracer.
+// It must be safe for concurrent use.
+type IDGenerator interface {
+	// SpanID returns the id of a new span, which must not be zero.
+	SpanID() uint64
+
+	// TraceID returns the id of the trace started by the root span with the
+	// given id. high holds the upper 64 bits of 128-bit trace ids and is zero
+	// for 64-bit ids.
+	TraceID(rootSpanID uint64) (high, low uint64)
 }
 
-func newRandSource() *randSource {
-	var seed int64
+// randomIDGenerator is the default IDGenerator: it generates random 63-bit
+// ids, the id of a trace being the one of its root span.
+type randomIDGenerator struct{}
 
-	max := big.NewInt(math.MaxInt64)
-	n, err := cryptorand.Int(cryptorand.Reader, max)
-	if err == nil {
-		seed = n.Int64()
-	} else {
-		log.Printf("%scannot generate random seed: %v; using current time\n", errorPrefix, err)
-		seed = time.Now().UnixNano()
-	}
+func (randomIDGenerator) SpanID() uint64 { return randomID() }
+
+func (randomIDGenerator) TraceID(rootSpanID uint64) (high, low uint64) { return 0, rootSpanID }
 
-	source := rand.NewSource(seed)
+// defaultIDGenerator is used by the tracers having no IDGenerator set.
+var defaultIDGenerator IDGenerator = randomIDGenerator{}
 
-	return &randSource{source: source}
+// sources holds random number sources, which are not safe for concurrent
+// use. Each id is generated with a source taken from the pool, so that
+// goroutines generating ids don't contend for a single source.
+var sources = sync.Pool{
+	New: func() interface{} {
+		return rand.NewSource(randomSeed())
+	},
 }
 
-func (rs *randSource) Int63() int64 {
-	rs.Lock()
-	n := rs.source.Int63()
-	rs.Unlock()
+// randomID returns a new random, non-zero, 63-bit id.
+func randomID() uint64 {
+	source := sources.Get().(rand.Source)
+	var n int64
+	for n == 0 {
+		n = source.Int63()
+	}
+	sources.Put(source)
+	return uint64(n)
+}
 
-	return n
+// randomSeed returns a cryptographically secure seed, falling back to the
+// current time if it can't be generated.
+func randomSeed() int64 {
+	max := big.NewInt(math.MaxInt64)
+	n, err := cryptorand.Int(cryptorand.Reader, max)
+	if err != nil {
+		packageLogger().Log(LevelWarn, "cannot generate random seed, using current time", "error", err)
+		return time.Now().UnixNano()
+	}
+	return n.Int64()
 }
 
-func (rs *randSource) Seed(seed int64) {
-	rs.Lock()
-	rs.Seed(seed)
-	rs.Unlock()
+// formatTraceIDHigh returns the upper 64 bits of a 128-bit trace id as they
+// are stored in the span meta.
+func formatTraceIDHigh(high uint64) string {
+	return fmt.Sprintf("%016x", high)
 }
@@ -0,0 +1,84 @@
+package tracer
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandomID(t *testing.T) {
+	assert := assert.New(t)
+
+	const n = 1000
+	ids := make(chan uint64, 4*n)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				ids <- NextSpanID()
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[uint64]bool, 4*n)
+	for id := range ids {
+		assert.NotEqual(uint64(0), id)
+		assert.Equal(uint64(0), id>>63, "ids are 63-bit")
+		assert.False(seen[id], "duplicate id")
+		seen[id] = true
+	}
+}
+
+// sequenceIDGenerator generates sequential ids, with 128-bit trace ids.
+type sequenceIDGenerator struct {
+	last uint64
+}
+
+func (g *sequenceIDGenerator) SpanID() uint64 {
+	return atomic.AddUint64(&g.last, 1)
+}
+
+func (g *sequenceIDGenerator) TraceID(rootSpanID uint64) (high, low uint64) {
+	return 0xabc, rootSpanID + 1000
+}
+
+func TestTracerIDGenerator(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	assert.Equal(defaultIDGenerator, tracer.IDGenerator())
+
+	tracer.SetIDGenerator(&sequenceIDGenerator{})
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	assert.Equal(uint64(1), root.SpanID)
+	assert.Equal(uint64(1001), root.TraceID)
+	assert.Equal("0000000000000abc", root.GetMeta("_dd.p.tid"))
+	assert.Equal(uint64(2), child.SpanID)
+	assert.Equal(uint64(1001), child.TraceID)
+	assert.Equal(uint64(1), child.ParentID)
+
+	// the default generator uses the id of the root span as trace id
+	tracer.SetIDGenerator(nil)
+	assert.Equal(defaultIDGenerator, tracer.IDGenerator())
+	root = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.Equal(root.SpanID, root.TraceID)
+	assert.Equal("", root.GetMeta("_dd.p.tid"))
+}
+
+// BenchmarkNextSpanID tests the performance of generating ids from many
+// goroutines at once.
+func BenchmarkNextSpanID(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			NextSpanID()
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package tracer
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracerShutdown(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	dropped, err := tracer.Shutdown(context.Background())
+	assert.Nil(err)
+	assert.Equal(0, dropped)
+	assert.Len(transport.Traces(), 1)
+
+	// traces finished once the tracer is stopped are dropped
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	assert.Equal(0, len(tracer.channels.trace))
+	assert.Equal(uint64(1), tracer.Stats().TracesDropped.Shutdown)
+
+	// it is idempotent, and flushing a stopped tracer doesn't block
+	dropped, err = tracer.Shutdown(context.Background())
+	assert.Nil(err)
+	assert.Equal(1, dropped)
+	tracer.Stop()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+}
+
+func TestTracerShutdownOpenTraces(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+
+	// a trace finished while the tracer shuts down is flushed if it's
+	// finished before the final flush, and counted as dropped otherwise
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	finished := make(chan struct{})
+	go func() {
+		root.Finish()
+		close(finished)
+	}()
+	dropped, err := tracer.Shutdown(context.Background())
+	assert.Nil(err)
+	<-finished
+	late := int(tracer.Stats().TracesDropped.Shutdown)
+	assert.True(dropped <= late)
+	assert.Equal(2, len(transport.Traces())+late)
+}
+
+func TestTracerShutdownConcurrent(t *testing.T) {
+	tracer, _ := getTestTracer()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				parent := tracer.NewRootSpan("pylons.request", "pylons", "/")
+				tracer.NewChildSpan("redis.command", parent).Finish()
+				parent.Finish()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			tracer.Shutdown(context.Background())
+		}()
+	}
+	wg.Wait()
+}
+
+// blockingTransport is a Transport whose SendTraces blocks until unblock
+// is closed.
+type blockingTransport struct {
+	*dummyTransport
+	unblock chan struct{}
+}
+
+func (t *blockingTransport) SendTraces(traces [][]*Span) (*http.Response, error) {
+	<-t.unblock
+	return t.dummyTransport.SendTraces(traces)
+}
+
+func TestTracerShutdownDeadline(t *testing.T) {
+	assert := assert.New(t)
+	transport := &blockingTransport{
+		dummyTransport: &dummyTransport{getEncoder: msgpackEncoderFactory},
+		unblock:        make(chan struct{}),
+	}
+	defer close(transport.unblock)
+	tracer := NewTracerTransport(transport)
+
+	for i := 0; i < 3; i++ {
+		tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	dropped, err := tracer.Shutdown(ctx)
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.Equal(3, dropped)
+}
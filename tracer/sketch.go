@@ -0,0 +1,155 @@
+package tracer
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// sketchRelativeAccuracy is the relative accuracy of the quantiles computed
+// from a sketch: any quantile is within 1% of its actual value.
+const sketchRelativeAccuracy = 0.01
+
+// sketch is a minimal implementation of DDSketch, a quantile sketch with
+// relative-error guarantees (see https://arxiv.org/abs/1908.10693). Values
+// are mapped to logarithmically sized bins, so that the number of bins grows
+// with the logarithm of the range of values, not with their number.
+//
+// It only supports positive values (plus zeros) and it encodes itself with
+// the protobuf format of github.com/DataDog/sketches-go, as expected by the
+// agent. A sketch is not safe for concurrent use.
+type sketch struct {
+	gamma      float64
+	multiplier float64 // 1/ln(gamma)
+	bins       map[int32]float64
+	zeroCount  float64
+	count      float64
+}
+
+func newSketch() *sketch {
+	gamma := (1 + sketchRelativeAccuracy) / (1 - sketchRelativeAccuracy)
+	return &sketch{
+		gamma:      gamma,
+		multiplier: 1 / math.Log(gamma),
+		bins:       make(map[int32]float64),
+	}
+}
+
+// index returns the index of the bin holding v, v being strictly positive.
+func (s *sketch) index(v float64) int32 {
+	return int32(math.Floor(math.Log(v) * s.multiplier))
+}
+
+// value returns the value representing the bin at the given index, such
+// that any value of the bin is within the relative accuracy of it.
+func (s *sketch) value(index int32) float64 {
+	return math.Pow(s.gamma, float64(index)) * (1 + sketchRelativeAccuracy)
+}
+
+// Add adds a value to the sketch. Negative values are counted as zeros.
+func (s *sketch) Add(v float64) {
+	s.count++
+	if v <= 0 {
+		s.zeroCount++
+		return
+	}
+	s.bins[s.index(v)]++
+}
+
+// Count returns the number of values added to the sketch.
+func (s *sketch) Count() float64 {
+	return s.count
+}
+
+// Quantile returns an approximation of the value at the given quantile,
+// which has to be between 0 and 1.
+func (s *sketch) Quantile(q float64) float64 {
+	if s.count == 0 || q < 0 || q > 1 {
+		return math.NaN()
+	}
+	rank := q * (s.count - 1)
+	if rank < s.zeroCount {
+		return 0
+	}
+	indexes := make([]int, 0, len(s.bins))
+	for i := range s.bins {
+		indexes = append(indexes, int(i))
+	}
+	sort.Ints(indexes)
+	n := s.zeroCount
+	for _, i := range indexes {
+		n += s.bins[int32(i)]
+		if n > rank {
+			return s.value(int32(i))
+		}
+	}
+	return s.value(int32(indexes[len(indexes)-1]))
+}
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// Encode returns the sketch encoded as a sketches-go DDSketch protobuf
+// message:
+//
+//	message DDSketch {
+//	  IndexMapping mapping = 1;    // gamma = 1, indexOffset = 2, interpolation = 3
+//	  Store positiveValues = 2;    // map<sint32, double> binCounts = 1
+//	  Store negativeValues = 3;
+//	  double zeroCount = 4;
+//	}
+func (s *sketch) Encode() []byte {
+	var mapping []byte
+	mapping = appendDouble(mapping, 1, s.gamma)
+	// indexOffset is 0 and interpolation is NONE, which are the defaults
+
+	indexes := make([]int, 0, len(s.bins))
+	for i := range s.bins {
+		indexes = append(indexes, int(i))
+	}
+	sort.Ints(indexes) // deterministic output
+	var store []byte
+	for _, i := range indexes {
+		var entry []byte
+		entry = appendTag(entry, 1, wireVarint)
+		entry = appendUvarint(entry, zigzag32(int32(i)))
+		entry = appendDouble(entry, 2, s.bins[int32(i)])
+		store = appendBytes(store, 1, entry)
+	}
+
+	var b []byte
+	b = appendBytes(b, 1, mapping)
+	b = appendBytes(b, 2, store)
+	if s.zeroCount > 0 {
+		b = appendDouble(b, 4, s.zeroCount)
+	}
+	return b
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendTag(b []byte, field int, wireType int) []byte {
+	return appendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+func appendDouble(b []byte, field int, v float64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+	return append(appendTag(b, field, wireFixed64), buf[:]...)
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendUvarint(appendTag(b, field, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+func zigzag32(v int32) uint64 {
+	return uint64(uint32(v<<1) ^ uint32(v>>31))
+}
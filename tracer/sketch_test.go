@@ -0,0 +1,69 @@
+package tracer
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSketchQuantiles(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newSketch()
+	assert.True(math.IsNaN(s.Quantile(0.5)))
+
+	for i := 1; i <= 1000; i++ {
+		s.Add(float64(i))
+	}
+	assert.Equal(float64(1000), s.Count())
+	for _, q := range []float64{0, 0.25, 0.5, 0.75, 0.95, 0.99, 1} {
+		expected := 1 + q*999
+		actual := s.Quantile(q)
+		assert.InDelta(expected, actual, expected*sketchRelativeAccuracy+1, "quantile %f", q)
+	}
+	assert.True(math.IsNaN(s.Quantile(2)))
+}
+
+func TestSketchZeros(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newSketch()
+	s.Add(0)
+	s.Add(-1)
+	s.Add(100)
+	assert.Equal(float64(3), s.Count())
+	assert.Equal(float64(0), s.Quantile(0.5))
+	assert.InDelta(100, s.Quantile(1), 100*sketchRelativeAccuracy)
+}
+
+func TestSketchEncode(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newSketch()
+	s.Add(1)
+	s.Add(1)
+	s.Add(0)
+	b := s.Encode()
+
+	// mapping: field 1, length 9, gamma as a double (field 1)
+	assert.Equal([]byte{0x0a, 9, 0x09}, b[:3])
+	assert.Equal(math.Float64bits(s.gamma), binary.LittleEndian.Uint64(b[3:11]))
+	// positive values store: field 2, one bin count entry (field 1) for index 0
+	assert.Equal([]byte{0x12, 13, 0x0a, 11, 0x08, 0x00, 0x11}, b[11:18])
+	assert.Equal(math.Float64bits(2), binary.LittleEndian.Uint64(b[18:26]))
+	// zero count: field 4
+	assert.Equal(byte(0x21), b[26])
+	assert.Equal(math.Float64bits(1), binary.LittleEndian.Uint64(b[27:35]))
+	assert.Len(b, 35)
+}
+
+func TestZigzag32(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(uint64(0), zigzag32(0))
+	assert.Equal(uint64(1), zigzag32(-1))
+	assert.Equal(uint64(2), zigzag32(1))
+	assert.Equal(uint64(3), zigzag32(-2))
+	assert.Equal(uint64(math.MaxUint32), zigzag32(math.MinInt32))
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"reflect"
-	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
 )
 
 const (
@@ -16,6 +19,7 @@ const (
 	errorStackKey = "error.stack"
 
 	samplingPriorityKey = "_sampling_priority_v1"
+	originKey           = "_dd.origin"
 )
 
 // Span represents a computation. Callers must call Finish when a span is
@@ -48,16 +52,18 @@ type Span struct {
 	// Resources should only be set on an app's top level spans.
 	Resource string `json:"resource"`
 
-	Type     string             `json:"type"`              // protocol associated with the span
-	Start    int64              `json:"start"`             // span start time expressed in nanoseconds since epoch
-	Duration int64              `json:"duration"`          // duration of the span expressed in nanoseconds
-	Meta     map[string]string  `json:"meta,omitempty"`    // arbitrary map of metadata
-	Metrics  map[string]float64 `json:"metrics,omitempty"` // arbitrary map of numeric metrics
-	SpanID   uint64             `json:"span_id"`           // identifier of this span
-	TraceID  uint64             `json:"trace_id"`          // identifier of the root span
-	ParentID uint64             `json:"parent_id"`         // identifier of the span's direct parent
-	Error    int32              `json:"error"`             // error status of the span; 0 means no errors
-	Sampled  bool               `json:"-"`                 // if this span is sampled (and should be kept/recorded) or not
+	Type     string             `json:"type"`                  // protocol associated with the span
+	Start    int64              `json:"start"`                 // span start time expressed in nanoseconds since epoch
+	Duration int64              `json:"duration"`              // duration of the span expressed in nanoseconds
+	Meta     map[string]string  `json:"meta,omitempty"`        // arbitrary map of metadata
+	Metrics  map[string]float64 `json:"metrics,omitempty"`     // arbitrary map of numeric metrics
+	Events   []SpanEvent        `json:"span_events,omitempty"` // timestamped events, see AddEvent
+	Links    []SpanLink         `json:"span_links,omitempty"`  // links to related spans, see AddLink
+	SpanID   uint64             `json:"span_id"`               // identifier of this span
+	TraceID  uint64             `json:"trace_id"`              // identifier of the root span
+	ParentID uint64             `json:"parent_id"`             // identifier of the span's direct parent
+	Error    int32              `json:"error"`                 // error status of the span; 0 means no errors
+	Sampled  bool               `json:"-"`                     // if this span is sampled (and should be kept/recorded) or not
 
 	sync.RWMutex
 	tracer   *Tracer // the tracer that generated this span
@@ -68,25 +74,100 @@ type Span struct {
 	// and also, parent == nil is used to identify root and top-level ("local root") spans.
 	parent *Span
 	buffer *spanBuffer
+
+	// globalMeta holds the meta of the tracer when the span was created, it
+	// is shared with other spans and must not be modified. It is merged into
+	// Meta when the span is flushed.
+	globalMeta map[string]string
+
+	// start is the time the span started, with a monotonic clock reading
+	// if the clock provides one, so that durations don't depend on wall
+	// clock changes.
+	start time.Time
+
+	// stack is where the span was created, only recorded if required by
+	// the leak detection, see LeakDetection.CaptureStacks.
+	stack []uintptr
+
+	// baggage holds the baggage items of the span, it is shared with its
+	// parent and children and must not be modified, see SetBaggageItem.
+	baggage map[string]string
 }
 
+// metaPool and metricsPool hold the maps of the flushed spans, which can be
+// reused, see Tracer.SetSpanPooling.
+var metaPool, metricsPool sync.Pool
+
 // NewSpan creates a new span. This is a low-level function, required for testing and advanced usage.
 // Most of the time one should prefer the Tracer NewRootSpan or NewChildSpan methods.
 func NewSpan(name, service, resource string, spanID, traceID, parentID uint64, tracer *Tracer) *Span {
-	return &Span{
-		Name:     name,
-		Service:  service,
-		Resource: resource,
-		Meta:     tracer.getAllMeta(),
-		SpanID:   spanID,
-		TraceID:  traceID,
-		ParentID: parentID,
-		Start:    now(),
-		Sampled:  true,
-		tracer:   tracer,
+	span := &Span{}
+	if tracer.SpanPoolingEnabled() {
+		span.Meta, _ = metaPool.Get().(map[string]string)
+		span.Metrics, _ = metricsPool.Get().(map[string]float64)
+	}
+	span.Name = name
+	span.Service = service
+	span.Resource = resource
+	span.SpanID = spanID
+	span.TraceID = traceID
+	span.ParentID = parentID
+	span.start = tracer.Clock().Now()
+	span.Start = span.start.UnixNano()
+	span.Sampled = true
+	span.tracer = tracer
+	span.globalMeta = tracer.globalMeta()
+	if tracer.LeakDetection().CaptureStacks {
+		span.stack = callers()
+	}
+	if tracer != nil {
+		atomic.AddUint64(&tracer.channels.counters.spansStarted, 1)
+	}
+	return span
+}
+
+// releaseSpan puts the maps of the given flushed span in the pools, to reuse
+// them. The span itself is not reused, since it can still be referenced, by
+// the spans of its trace created later for instance, so it is left without
+// meta and metrics.
+func releaseSpan(s *Span) {
+	s.Lock()
+	meta, metrics := s.Meta, s.Metrics
+	s.Meta, s.Metrics = nil, nil
+	s.Unlock()
+	if meta != nil {
+		for k := range meta {
+			delete(meta, k)
+		}
+		metaPool.Put(meta)
+	}
+	if metrics != nil {
+		for k := range metrics {
+			delete(metrics, k)
+		}
+		metricsPool.Put(metrics)
 	}
 }
 
+// applyGlobalMeta merges the meta of the tracer into the span, the span meta
+// taking precedence.
+func (s *Span) applyGlobalMeta() {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.globalMeta) == 0 {
+		return
+	}
+	if s.Meta == nil {
+		s.Meta = make(map[string]string, len(s.globalMeta))
+	}
+	for k, v := range s.globalMeta {
+		if _, ok := s.Meta[k]; !ok {
+			s.Meta[k] = v
+		}
+	}
+	s.globalMeta = nil
+}
+
 // setMeta adds an arbitrary meta field to the current Span. The span
 // must be locked outside of this function
 func (s *Span) setMeta(key, value string) {
@@ -115,7 +196,9 @@ func (s *Span) SetMeta(key, value string) {
 		return
 	}
 
-	s.Lock()
+	if !s.lockMutable() {
+		return
+	}
 	defer s.Unlock()
 
 	s.setMeta(key, value)
@@ -138,10 +221,10 @@ func (s *Span) GetMeta(key string) string {
 	}
 	s.RLock()
 	defer s.RUnlock()
-	if s.Meta == nil {
-		return ""
+	if v, ok := s.Meta[key]; ok {
+		return v
 	}
-	return s.Meta[key]
+	return s.globalMeta[key]
 }
 
 // SetMetrics adds a metric field to the current Span.
@@ -161,15 +244,10 @@ func (s *Span) SetMetric(key string, val float64) {
 		return
 	}
 
-	s.Lock()
-	defer s.Unlock()
-
-	// We don't lock spans when flushing, so we could have a data race when
-	// modifying a span as it's being flushed. This protects us against that
-	// race, since spans are marked `finished` before we flush them.
-	if s.finished {
+	if !s.lockMutable() {
 		return
 	}
+	defer s.Unlock()
 
 	if s.Metrics == nil {
 		s.Metrics = make(map[string]float64)
@@ -177,28 +255,196 @@ func (s *Span) SetMetric(key string, val float64) {
 	s.Metrics[key] = val
 }
 
+// SetTag adds a tag to the span, routing the value according to its type:
+// numbers are stored as metrics, errors are set with SetError and any other
+// value is stored as meta, using its String method if it has one. The tags
+// defined in the ext package for the service, resource, type, sampling
+// priority and error status set the matching property of the span instead,
+// while the HTTP status code is always stored as meta.
+// If the Span has been finished, it will not be modified by this method.
+func (s *Span) SetTag(key string, value interface{}) {
+	if s == nil || value == nil {
+		return
+	}
+
+	switch key {
+	case ext.ServiceName, ext.ResourceName, ext.SpanType:
+		if !s.lockMutable() {
+			return
+		}
+		defer s.Unlock()
+		switch key {
+		case ext.ServiceName:
+			s.Service = tagString(value)
+		case ext.ResourceName:
+			s.Resource = tagString(value)
+		case ext.SpanType:
+			s.Type = tagString(value)
+		}
+		return
+	case ext.SamplingPriority:
+		if priority, ok := toFloat64(value); ok {
+			s.SetSamplingPriority(int(priority))
+		} else if priority, err := strconv.Atoi(tagString(value)); err == nil {
+			s.SetSamplingPriority(priority)
+		}
+		return
+	case ext.HTTPCode:
+		s.SetMeta(key, tagString(value))
+		return
+	case ext.Error:
+		if err, ok := value.(error); ok {
+			s.setError(err, 1)
+			return
+		}
+		failed, ok := value.(bool)
+		if !ok {
+			failed, _ = strconv.ParseBool(tagString(value))
+		}
+		s.setErrorStatus(failed)
+		return
+	}
+
+	if v, ok := toFloat64(value); ok {
+		s.SetMetric(key, v)
+		return
+	}
+	if err, ok := value.(error); ok {
+		s.setError(err, 1)
+		return
+	}
+	s.SetMeta(key, tagString(value))
+}
+
+// setErrorStatus sets the error status of the span, without attaching any
+// error details to it.
+func (s *Span) setErrorStatus(failed bool) {
+	if !s.lockMutable() {
+		return
+	}
+	defer s.Unlock()
+	if failed {
+		s.Error = 1
+	} else {
+		s.Error = 0
+	}
+}
+
+// SetTraceTag adds a tag to the local root span of the trace, that is the
+// first span of the trace created in this process, as if SetTag was called
+// on it. It is meant for the tags describing the whole trace, such as a
+// customer id, which are often only known deep in the call tree.
+// If the local root span has been finished, it will not be modified.
+func (s *Span) SetTraceTag(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.localRoot().SetTag(key, value)
+}
+
+// localRoot returns the local root span of the trace of the span.
+func (s *Span) localRoot() *Span {
+	// parent is set when the span is created and never changes after
+	for s.parent != nil {
+		s = s.parent
+	}
+	return s
+}
+
+// tagString returns the string representation of a tag value.
+func tagString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
+
+// toFloat64 converts a numeric value to a float64, returning false if the
+// value is not a number.
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
 // SetError stores an error object within the span meta. The Error status is
 // updated and the error.Error() string is included with a default meta key.
+// The stack trace is recorded according to the ErrorConfig of the tracer and,
+// if the error wraps other errors, the type and message of its root cause
+// are recorded as well.
 // If the Span has been finished, it will not be modified by this method.
 func (s *Span) SetError(err error) {
+	s.setError(err, 1)
+}
+
+// setError implements SetError, skip being the number of frames between
+// setError and the user code, which are not part of the stack trace.
+func (s *Span) setError(err error, skip int) {
 	if err == nil || s == nil {
 		return
 	}
 
-	s.Lock()
-	defer s.Unlock()
-	// We don't lock spans when flushing, so we could have a data race when
-	// modifying a span as it's being flushed. This protects us against that
-	// race, since spans are marked `finished` before we flush them.
-	if s.finished {
+	cfg := s.tracer.ErrorConfig()
+	chain := causeChain(err)
+	root := chain[len(chain)-1]
+
+	// compute the stack before taking the lock, as it can be expensive
+	var stack string
+	if !cfg.NoStack {
+		var ok bool
+		// the deepest stack is the closest to where the error was created
+		for i := len(chain) - 1; i >= 0 && !ok; i-- {
+			stack, ok = errorStack(chain[i], cfg.depth())
+		}
+		if !ok {
+			stack = captureStack(skip+1+cfg.SkipFrames, cfg.depth())
+		}
+	}
+
+	if !s.lockMutable() {
 		return
 	}
+	defer s.Unlock()
 	s.Error = 1
 
 	s.setMeta(errorMsgKey, err.Error())
 	s.setMeta(errorTypeKey, reflect.TypeOf(err).String())
-	stack := debug.Stack()
-	s.setMeta(errorStackKey, string(stack))
+	if !cfg.NoStack {
+		s.setMeta(errorStackKey, stack)
+	}
+	if len(chain) > 1 {
+		s.setMeta(errorCauseMsgKey, root.Error())
+		s.setMeta(errorCauseTypeKey, reflect.TypeOf(root).String())
+	}
 }
 
 // Finish closes this Span (but not its children) providing the duration
@@ -207,38 +453,51 @@ func (s *Span) SetError(err error) {
 // current Span. Once a Span has been finished, methods that modify the Span
 // will become no-ops.
 func (s *Span) Finish() {
-	s.finish(now())
+	if s == nil {
+		return
+	}
+	s.finish(s.tracer.Clock().Now())
 }
 
 // FinishWithTime closes this Span at the given `finishTime`. The
 // behavior is the same as `Finish()`.
 func (s *Span) FinishWithTime(finishTime int64) {
-	s.finish(finishTime)
+	s.finish(time.Unix(0, finishTime))
 }
 
-func (s *Span) finish(finishTime int64) {
+func (s *Span) finish(finishTime time.Time) {
 	if s == nil {
 		return
 	}
 
+	var truncated, dropped int
 	s.Lock()
 	finished := s.finished
 	if !finished {
 		if s.Duration == 0 {
-			s.Duration = finishTime - s.Start
+			s.Duration = s.duration(finishTime)
 		}
+		truncated, dropped = s.applyLimits(s.tracer.SpanLimits())
 		s.finished = true
 	}
 	s.Unlock()
 
 	if finished {
 		// no-op, called twice, no state change...
+		s.misused(MisuseDoubleFinish)
 		return
 	}
+	if s.tracer != nil {
+		atomic.AddUint64(&s.tracer.channels.counters.spansFinished, 1)
+		s.tracer.countLimits(truncated, dropped)
+		if s.tracer.tracksOpenTraces() {
+			s.tracer.open.finish(s)
+		}
+	}
 
 	if s.buffer == nil {
 		if s.tracer != nil {
-			s.tracer.channels.pushErr(&errorNoSpanBuf{SpanName: s.Name})
+			s.tracer.channels.pushErr(&ErrorNoSpanBuf{SpanName: s.Name})
 		}
 		return
 	}
@@ -248,11 +507,14 @@ func (s *Span) finish(finishTime int64) {
 		return
 	}
 
-	// If not sampled, drop it
-	if !s.Sampled {
-		return
+	// Stats are computed on every span, before it's possibly dropped by
+	// the sampler, so that they don't depend on the sample rate
+	if s.tracer != nil && s.tracer.StatsComputationEnabled() {
+		s.tracer.stats.add(s)
 	}
 
+	// Unsampled traces are dropped once finished, since the decision can
+	// still be changed with Keep or Drop until then
 	s.buffer.AckFinish() // put data in channel only if trace is completely finished
 
 	// It's important that when Finish() exits, the data is put in
@@ -261,13 +523,35 @@ func (s *Span) finish(finishTime int64) {
 	// the channel is).
 }
 
+// topLevel returns true if the span is a top-level span, that is a local
+// root span or a span whose service differs from its parent's one.
+func (s *Span) topLevel() bool {
+	if s.parent == nil {
+		return true
+	}
+	s.parent.RLock()
+	defer s.parent.RUnlock()
+	return s.parent.Service != s.Service
+}
+
+// duration returns the duration of the span finished at the given time. It
+// is measured with the monotonic clock when both the start and finish times
+// have a reading, unless Start has been changed since the span started.
+// The span must be locked.
+func (s *Span) duration(finishTime time.Time) int64 {
+	if s.start.IsZero() || s.start.UnixNano() != s.Start {
+		return finishTime.UnixNano() - s.Start
+	}
+	return int64(finishTime.Sub(s.start))
+}
+
 // FinishWithErr marks a span finished and sets the given error if it's
 // non-nil.
 func (s *Span) FinishWithErr(err error) {
 	if s == nil {
 		return
 	}
-	s.SetError(err)
+	s.setError(err, 1)
 	s.Finish()
 }
 
@@ -315,24 +599,80 @@ func (s *Span) Tracer() *Tracer {
 	return s.tracer
 }
 
-// SetSamplingPriority sets the sampling priority.
+// SetSamplingPriority sets the sampling priority of the span. To keep or
+// drop the whole trace, use Keep or Drop instead.
 func (s *Span) SetSamplingPriority(priority int) {
 	s.SetMetric(samplingPriorityKey, float64(priority))
 }
 
-// HasSamplingPriority returns true if sampling priority is set.
+// HasSamplingPriority returns true if sampling priority is set, on the span
+// or on its trace with Keep or Drop.
 // It can be defined to either zero or non-zero.
 func (s *Span) HasSamplingPriority() bool {
+	if _, ok := s.buffer.samplingPriority(); ok {
+		return true
+	}
 	_, hasSamplingPriority := s.Metrics[samplingPriorityKey]
 	return hasSamplingPriority
 }
 
-// GetSamplingPriority gets the sampling priority.
+// GetSamplingPriority gets the sampling priority. The priority set on the
+// trace with Keep or Drop takes precedence over the one of the span.
 func (s *Span) GetSamplingPriority() int {
+	if priority, ok := s.buffer.samplingPriority(); ok {
+		return priority
+	}
 	return int(s.Metrics[samplingPriorityKey])
 }
 
+// Keep keeps the trace of the span, whatever the decision of the sampler,
+// typically because it has an error or is an important request. It can be
+// called on any span of the trace until the trace is flushed, and the
+// decision is propagated to the other services with the sampling priority.
+func (s *Span) Keep() {
+	s.setTracePriority(ext.PriorityUserKeep)
+}
+
+// Drop drops the trace of the span, whatever the decision of the sampler.
+// Like Keep, it applies to the whole trace and is propagated.
+func (s *Span) Drop() {
+	s.setTracePriority(ext.PriorityUserReject)
+}
+
+// setTracePriority sets the sampling priority of the trace of the span,
+// which is applied to its local root when the trace is flushed.
+func (s *Span) setTracePriority(priority int) {
+	if s == nil {
+		return
+	}
+	s.buffer.setPriority(priority)
+	s.Lock()
+	if !s.finished {
+		s.Sampled = priority > ext.PriorityAutoReject
+		if s.buffer == nil {
+			if s.Metrics == nil {
+				s.Metrics = make(map[string]float64)
+			}
+			s.Metrics[samplingPriorityKey] = float64(priority)
+		}
+	}
+	s.Unlock()
+}
+
+// SetOrigin sets the origin of the trace, that is the product which started
+// it (e.g. "synthetics"). It is inherited by children and propagated across
+// process boundaries along with the sampling priority.
+func (s *Span) SetOrigin(origin string) {
+	s.SetMeta(originKey, origin)
+}
+
+// GetOrigin returns the origin of the trace, or the empty string if it
+// has not been set.
+func (s *Span) GetOrigin() string {
+	return s.GetMeta(originKey)
+}
+
 // NextSpanID returns a new random span id.
 func NextSpanID() uint64 {
-	return uint64(randGen.Int63())
+	return randomID()
 }
@@ -3,6 +3,8 @@ package tracer
 import (
 	"context"
 	"errors"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -110,6 +112,67 @@ func TestSpanSetMetric(t *testing.T) {
 	assert.Equal(0.0, span.Metrics["finished.test"])
 }
 
+func TestSpanSetTag(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+
+	span.SetTag("string", "value")
+	span.SetTag("bool", true)
+	span.SetTag("int", 42)
+	span.SetTag("uint16", uint16(7))
+	span.SetTag("float", 0.5)
+	span.SetTag("stringer", stringer("hello"))
+	span.SetTag("struct", struct{ N int }{5})
+	span.SetTag("nil", nil)
+	assert.Equal("value", span.Meta["string"])
+	assert.Equal("true", span.Meta["bool"])
+	assert.Equal(float64(42), span.Metrics["int"])
+	assert.Equal(float64(7), span.Metrics["uint16"])
+	assert.Equal(0.5, span.Metrics["float"])
+	assert.Equal("hello", span.Meta["stringer"])
+	assert.Equal("{5}", span.Meta["struct"])
+	_, ok := span.Meta["nil"]
+	assert.False(ok)
+
+	span.SetTag("err", errors.New("boom"))
+	assert.Equal(int32(1), span.Error)
+	assert.Equal("boom", span.Meta["error.msg"])
+	assert.True(strings.HasPrefix(span.Meta["error.stack"], "github.com/DataDog/dd-trace-go/tracer.TestSpanSetTag\n"))
+	_, ok = span.Meta["err"]
+	assert.False(ok)
+
+	// special tags
+	span.SetTag(ext.ServiceName, "django")
+	span.SetTag(ext.ResourceName, "/home")
+	span.SetTag(ext.SpanType, ext.HTTPType)
+	span.SetTag(ext.SamplingPriority, ext.PriorityUserKeep)
+	span.SetTag(ext.HTTPCode, 404)
+	assert.Equal("django", span.Service)
+	assert.Equal("/home", span.Resource)
+	assert.Equal("http", span.Type)
+	assert.Equal(ext.PriorityUserKeep, span.GetSamplingPriority())
+	assert.Equal("404", span.Meta["http.status_code"])
+	span.SetTag(ext.SamplingPriority, "-1")
+	assert.Equal(ext.PriorityUserReject, span.GetSamplingPriority())
+	span.SetTag(ext.Error, true)
+	assert.Equal(int32(1), span.Error)
+	assert.NotContains(span.Meta, ext.Error)
+	span.SetTag(ext.Error, "false")
+	assert.Equal(int32(0), span.Error)
+
+	// operating on a finished span is a no-op
+	span.Finish()
+	span.SetTag(ext.ServiceName, "flask")
+	span.SetTag("late", 1)
+	assert.Equal("django", span.Service)
+	_, ok = span.Metrics["late"]
+	assert.False(ok)
+
+	var empty *Span
+	empty.SetTag("key", "value")
+}
+
 func TestSpanError(t *testing.T) {
 	assert := assert.New(t)
 	tracer := NewTracer()
@@ -149,6 +212,57 @@ func TestSpanError_Typed(t *testing.T) {
 	assert.NotEqual("", span.Meta["error.stack"])
 }
 
+func TestSpanErrorConfig(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+
+	// by default the stack starts at the caller of SetError
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.SetError(errors.New("boom"))
+	assert.True(strings.HasPrefix(span.Meta["error.stack"], "github.com/DataDog/dd-trace-go/tracer.TestSpanErrorConfig\n"))
+
+	tracer.SetErrorConfig(ErrorConfig{NoStack: true})
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.SetError(errors.New("boom"))
+	assert.Equal("boom", span.Meta["error.msg"])
+	_, ok := span.Meta["error.stack"]
+	assert.False(ok)
+
+	tracer.SetErrorConfig(ErrorConfig{StackDepth: 2})
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.SetError(errors.New("boom"))
+	assert.Equal(2, strings.Count(span.Meta["error.stack"], "\n\t"))
+
+	tracer.SetErrorConfig(ErrorConfig{SkipFrames: 1})
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	setErrorHelper(span, errors.New("boom"))
+	assert.True(strings.HasPrefix(span.Meta["error.stack"], "github.com/DataDog/dd-trace-go/tracer.TestSpanErrorConfig\n"))
+}
+
+func TestSpanErrorCause(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+
+	root := newStackError("connection refused")
+	err := &wrapError{msg: "query failed", cause: &causeError{msg: "db error", cause: root}}
+	span.SetError(err)
+	assert.Equal("query failed", span.Meta["error.msg"])
+	assert.Equal("*tracer.wrapError", span.Meta["error.type"])
+	assert.Equal("connection refused", span.Meta["error.cause.msg"])
+	assert.Equal("*tracer.stackError", span.Meta["error.cause.type"])
+	// the stack of the root cause is preferred to the current one
+	assert.True(strings.HasPrefix(span.Meta["error.stack"], "github.com/DataDog/dd-trace-go/tracer.newStackError\n"))
+
+	// errors wrapping nothing have no cause
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.SetError(&boomError{})
+	_, ok := span.Meta["error.cause.msg"]
+	assert.False(ok)
+	_, ok = span.Meta["error.cause.type"]
+	assert.False(ok)
+}
+
 func TestEmptySpan(t *testing.T) {
 	// ensure the empty span won't crash the app
 	var span Span
@@ -284,6 +398,59 @@ func TestSpanSamplingPriority(t *testing.T) {
 	}
 }
 
+func TestSpanOrigin(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+
+	span := tracer.NewRootSpan("my.name", "my.service", "my.resource")
+	assert.Equal("", span.GetOrigin())
+	assert.Equal("", tracer.NewChildSpan("my.child", span).GetOrigin())
+
+	span.SetOrigin("synthetics")
+	assert.Equal("synthetics", span.GetOrigin())
+	assert.Equal("synthetics", span.Meta["_dd.origin"])
+	assert.Equal("synthetics", tracer.NewChildSpan("my.child", span).GetOrigin())
+}
+
 type boomError struct{}
 
 func (e *boomError) Error() string { return "boom" }
+
+func setErrorHelper(span *Span, err error) { span.SetError(err) }
+
+// wrapError wraps errors using the Unwrap convention.
+type wrapError struct {
+	msg   string
+	cause error
+}
+
+func (e *wrapError) Error() string { return e.msg }
+func (e *wrapError) Unwrap() error { return e.cause }
+
+// causeError wraps errors using the Cause convention.
+type causeError struct {
+	msg   string
+	cause error
+}
+
+func (e *causeError) Error() string { return e.msg }
+func (e *causeError) Cause() error  { return e.cause }
+
+// stackError records the stack trace where it has been created.
+type stackError struct {
+	msg   string
+	stack []uintptr
+}
+
+func newStackError(msg string) *stackError {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+	return &stackError{msg: msg, stack: pcs[:n]}
+}
+
+func (e *stackError) Error() string         { return e.msg }
+func (e *stackError) StackTrace() []uintptr { return e.stack }
+
+type stringer string
+
+func (s stringer) String() string { return string(s) }
@@ -0,0 +1,173 @@
+package tracer
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+const (
+	// defaultStackDepth is the default maximum number of frames captured
+	// in an error stack.
+	defaultStackDepth = 64
+	// callerStackDepth is the maximum number of frames of the stacks
+	// recorded by callers.
+	callerStackDepth = 32
+	// maxCauseChain is the maximum number of wrapped errors we walk through
+	// to find the root cause of an error, protecting us against cycles.
+	maxCauseChain = 100
+
+	errorCauseMsgKey  = "error.cause.msg"
+	errorCauseTypeKey = "error.cause.type"
+)
+
+// ErrorConfig configures how errors are recorded by Span.SetError.
+type ErrorConfig struct {
+	// NoStack disables the capture of stack traces.
+	NoStack bool
+	// StackDepth is the maximum number of frames of a stack trace.
+	// Zero means the default (64 frames).
+	StackDepth int
+	// SkipFrames is the number of frames skipped when capturing a stack
+	// trace, starting from the caller of Span.SetError. It is useful when
+	// errors are always recorded through the same helper functions.
+	SkipFrames int
+}
+
+// depth returns the maximum number of frames of a stack trace.
+func (cfg ErrorConfig) depth() int {
+	if cfg.StackDepth <= 0 {
+		return defaultStackDepth
+	}
+	return cfg.StackDepth
+}
+
+// captureStack returns the current stack trace, skip being the number of
+// frames skipped above the caller of captureStack.
+func captureStack(skip, depth int) string {
+	pcs := make([]uintptr, depth)
+	// skip runtime.Callers, captureStack and the given frames
+	n := runtime.Callers(skip+2, pcs)
+	return formatStack(pcs[:n])
+}
+
+// formatStack formats the given program counters as a stack trace.
+func formatStack(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&buf, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return buf.String()
+}
+
+// errorStack returns the stack trace recorded by the error when it was
+// created, if its type provides one. Errors created with the
+// github.com/pkg/errors package are supported, as is any error having a
+// StackTrace method returning a slice of program counters.
+func errorStack(err error, depth int) (string, bool) {
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() {
+		return "", false
+	}
+	typ := method.Type()
+	if typ.NumIn() != 0 || typ.NumOut() != 1 {
+		return "", false
+	}
+	if out := typ.Out(0); out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return "", false
+	}
+	trace := method.Call(nil)[0]
+	n := trace.Len()
+	if n > depth {
+		n = depth
+	}
+	pcs := make([]uintptr, n)
+	for i := range pcs {
+		pcs[i] = uintptr(trace.Index(i).Uint())
+	}
+	return formatStack(pcs), true
+}
+
+// unwrap returns the error wrapped by err, or nil if there is none. Both
+// the Cause() (github.com/pkg/errors) and the Unwrap() conventions are
+// supported.
+func unwrap(err error) error {
+	switch e := err.(type) {
+	case interface {
+		Cause() error
+	}:
+		return e.Cause()
+	case interface {
+		Unwrap() error
+	}:
+		return e.Unwrap()
+	}
+	return nil
+}
+
+// causeChain returns the chain of errors wrapped by err, starting with err
+// and ending with its root cause.
+func causeChain(err error) []error {
+	chain := []error{err}
+	for i := 0; i < maxCauseChain; i++ {
+		cause := unwrap(err)
+		if cause == nil {
+			break
+		}
+		chain = append(chain, cause)
+		err = cause
+	}
+	return chain
+}
+
+// callers returns the program counters of the stack of its caller.
+func callers() []uintptr {
+	pcs := make([]uintptr, callerStackDepth)
+	// skip runtime.Callers and callers
+	n := runtime.Callers(2, pcs)
+	return pcs[:n]
+}
+
+// tracerDir is the directory of the source files of this package.
+var tracerDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// formatCallerStack formats the stack returned by callers, leaving out its
+// first frames when they belong to this package, so that it starts with
+// the code calling the tracer.
+func formatCallerStack(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	frames := runtime.CallersFrames(pcs)
+	inTracer := true
+	for {
+		frame, more := frames.Next()
+		if inTracer && filepath.Dir(frame.File) == tracerDir && !strings.HasSuffix(frame.File, "_test.go") {
+			if !more {
+				break
+			}
+			continue
+		}
+		inTracer = false
+		fmt.Fprintf(&buf, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,184 @@
+package tracer
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+const (
+	// statsBucketDuration is the duration of the time buckets in which
+	// trace stats are aggregated.
+	statsBucketDuration = int64(10 * time.Second)
+
+	// clientComputedStatsHeader tells the agent that the stats of the sent
+	// traces have already been computed, so it won't compute them again.
+	clientComputedStatsHeader = "Datadog-Client-Computed-Stats"
+)
+
+// statsKey is the set of dimensions spans are aggregated on.
+type statsKey struct {
+	service, name, resource, spanType string
+	httpStatusCode                    uint32
+	synthetics                        bool
+}
+
+// groupedStats holds the aggregated stats of all the spans sharing a statsKey
+// within a time bucket.
+type groupedStats struct {
+	hits, topLevelHits, errors uint64
+	duration                   uint64 // total duration, in nanoseconds
+	okDistribution             *sketch
+	errDistribution            *sketch
+}
+
+// concentrator aggregates the stats of finished spans in time buckets, so
+// that the agent receives exact hits, errors and latency distributions
+// whatever the sample rate is. It is safe for concurrent use.
+type concentrator struct {
+	mu      sync.Mutex
+	buckets map[int64]map[statsKey]*groupedStats // bucket start -> stats
+}
+
+func newConcentrator() *concentrator {
+	return &concentrator{
+		buckets: make(map[int64]map[statsKey]*groupedStats),
+	}
+}
+
+// add aggregates the given span if it is top-level or measured. The span
+// must be finished.
+func (c *concentrator) add(s *Span) {
+	topLevel := s.topLevel()
+
+	s.RLock()
+	if !topLevel && s.Metrics[ext.Measured] != 1 {
+		s.RUnlock()
+		return
+	}
+	key := statsKey{
+		service:    s.Service,
+		name:       s.Name,
+		resource:   s.Resource,
+		spanType:   s.Type,
+		synthetics: strings.HasPrefix(s.Meta[originKey], "synthetics"),
+	}
+	if code, err := strconv.ParseUint(s.Meta[ext.HTTPCode], 10, 32); err == nil {
+		key.httpStatusCode = uint32(code)
+	}
+	failed := s.Error != 0
+	duration := s.Duration
+	end := s.Start + s.Duration
+	s.RUnlock()
+
+	if duration < 0 {
+		duration = 0
+	}
+	bucket := end - end%statsBucketDuration
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	groups, ok := c.buckets[bucket]
+	if !ok {
+		groups = make(map[statsKey]*groupedStats)
+		c.buckets[bucket] = groups
+	}
+	gs, ok := groups[key]
+	if !ok {
+		gs = &groupedStats{
+			okDistribution:  newSketch(),
+			errDistribution: newSketch(),
+		}
+		groups[key] = gs
+	}
+	gs.hits++
+	if topLevel {
+		gs.topLevelHits++
+	}
+	gs.duration += uint64(duration)
+	if failed {
+		gs.errors++
+		gs.errDistribution.Add(float64(duration))
+	} else {
+		gs.okDistribution.Add(float64(duration))
+	}
+}
+
+// flush removes and returns the buckets which are complete at the given
+// time. If force is true, all the buckets are returned, including the
+// current one.
+func (c *concentrator) flush(now int64, force bool) []statsBucket {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var buckets []statsBucket
+	for start, groups := range c.buckets {
+		if !force && start+statsBucketDuration > now {
+			// still in progress
+			continue
+		}
+		bucket := statsBucket{
+			Start:    uint64(start),
+			Duration: uint64(statsBucketDuration),
+			Stats:    make([]groupedStatsPayload, 0, len(groups)),
+		}
+		for key, gs := range groups {
+			bucket.Stats = append(bucket.Stats, groupedStatsPayload{
+				Service:        key.service,
+				Name:           key.name,
+				Resource:       key.resource,
+				HTTPStatusCode: key.httpStatusCode,
+				Type:           key.spanType,
+				Hits:           gs.hits,
+				Errors:         gs.errors,
+				Duration:       gs.duration,
+				OkSummary:      gs.okDistribution.Encode(),
+				ErrorSummary:   gs.errDistribution.Encode(),
+				Synthetics:     key.synthetics,
+				TopLevelHits:   gs.topLevelHits,
+			})
+		}
+		buckets = append(buckets, bucket)
+		delete(c.buckets, start)
+	}
+	return buckets
+}
+
+// statsPayload is the payload sent to the agent stats endpoint. Field names
+// are the ones expected by the agent and must not be changed.
+type statsPayload struct {
+	Hostname      string
+	Env           string
+	Version       string
+	Stats         []statsBucket
+	Lang          string
+	TracerVersion string
+	Sequence      uint64
+}
+
+// statsBucket holds the stats of a time bucket.
+type statsBucket struct {
+	Start    uint64 // nanoseconds since epoch
+	Duration uint64 // nanoseconds
+	Stats    []groupedStatsPayload
+}
+
+// groupedStatsPayload holds the stats of spans sharing the same dimensions.
+type groupedStatsPayload struct {
+	Service        string
+	Name           string
+	Resource       string
+	HTTPStatusCode uint32
+	Type           string
+	Hits           uint64
+	Errors         uint64
+	Duration       uint64
+	OkSummary      []byte // DDSketch of the durations of the spans with no errors
+	ErrorSummary   []byte // DDSketch of the durations of the spans with errors
+	Synthetics     bool
+	TopLevelHits   uint64
+}
@@ -0,0 +1,227 @@
+package tracer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+)
+
+// statsByResource returns the grouped stats of the given buckets by resource.
+func statsByResource(buckets []statsBucket) map[string]groupedStatsPayload {
+	stats := make(map[string]groupedStatsPayload)
+	for _, b := range buckets {
+		for _, gs := range b.Stats {
+			stats[gs.Resource] = gs
+		}
+	}
+	return stats
+}
+
+func TestConcentratorAdd(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+	defer tracer.Stop()
+	c := newConcentrator()
+
+	start := 10 * statsBucketDuration
+	newSpan := func(resource string, parent *Span) *Span {
+		var span *Span
+		if parent == nil {
+			span = tracer.NewRootSpan("http.request", "web", resource)
+		} else {
+			span = tracer.NewChildSpan("child", parent)
+			span.Resource = resource
+		}
+		span.Start = start
+		span.Duration = int64(time.Millisecond)
+		return span
+	}
+
+	root := newSpan("/home", nil)
+	root.SetMeta(ext.HTTPCode, "200")
+	c.add(root)
+	failed := newSpan("/home", nil)
+	failed.SetMeta(ext.HTTPCode, "200")
+	failed.Error = 1
+	failed.Duration = int64(time.Second)
+	c.add(failed)
+	c.add(newSpan("/home", nil)) // no status code, aggregated apart
+
+	// children of the same service are not aggregated unless measured
+	c.add(newSpan("child", root))
+	measured := newSpan("measured", root)
+	measured.SetMetric(ext.Measured, 1)
+	c.add(measured)
+	other := newSpan("other-service", root)
+	other.Service = "db"
+	c.add(other)
+
+	// the current bucket is not flushed, unless forced
+	assert.Len(c.flush(start+statsBucketDuration-1, false), 0)
+	buckets := c.flush(start+statsBucketDuration, false)
+	assert.Len(buckets, 1)
+	assert.Len(c.flush(start+statsBucketDuration, true), 0)
+
+	b := buckets[0]
+	assert.Equal(uint64(start), b.Start)
+	assert.Equal(uint64(statsBucketDuration), b.Duration)
+	assert.Len(b.Stats, 4)
+
+	var home, homeNoCode groupedStatsPayload
+	for _, gs := range b.Stats {
+		if gs.Resource == "/home" && gs.HTTPStatusCode == 200 {
+			home = gs
+		} else if gs.Resource == "/home" {
+			homeNoCode = gs
+		}
+	}
+	assert.Equal("web", home.Service)
+	assert.Equal("http.request", home.Name)
+	assert.Equal(uint64(2), home.Hits)
+	assert.Equal(uint64(2), home.TopLevelHits)
+	assert.Equal(uint64(1), home.Errors)
+	assert.Equal(uint64(time.Second+time.Millisecond), home.Duration)
+	assert.NotEmpty(home.OkSummary)
+	assert.NotEmpty(home.ErrorSummary)
+	assert.Equal(uint64(1), homeNoCode.Hits)
+
+	stats := statsByResource(buckets)
+	assert.NotContains(stats, "child")
+	assert.Equal(uint64(1), stats["measured"].Hits)
+	assert.Equal(uint64(0), stats["measured"].TopLevelHits)
+	assert.Equal(uint64(1), stats["other-service"].TopLevelHits)
+}
+
+func TestConcentratorBuckets(t *testing.T) {
+	assert := assert.New(t)
+	c := newConcentrator()
+
+	for i := int64(0); i < 3; i++ {
+		span := NewSpan("name", "service", "resource", 1, 1, 0, nil)
+		span.Start = i * statsBucketDuration
+		span.Duration = 1
+		span.finished = true
+		c.add(span)
+	}
+
+	buckets := c.flush(2*statsBucketDuration, false)
+	assert.Len(buckets, 2)
+	buckets = c.flush(2*statsBucketDuration, true)
+	assert.Len(buckets, 1)
+	assert.Equal(uint64(2*statsBucketDuration), buckets[0].Start)
+}
+
+func TestTracerStatsBeforeSampling(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0)
+	tracer.SetMeta(ext.Environment, "prod")
+
+	// disabled by default
+	tracer.NewRootSpan("http.request", "web", "/home").Finish()
+	tracer.ForceFlush()
+	assert.Len(transport.Stats(), 0)
+
+	tracer.SetStatsComputation(true)
+	for i := 0; i < 10; i++ {
+		root := tracer.NewRootSpan("http.request", "web", "/home")
+		tracer.NewChildSpan("sql.query", root).Finish()
+		root.Finish()
+	}
+	tracer.ForceFlush()
+
+	// every trace has been dropped by the sampler, but stats are exact
+	assert.Len(transport.Traces(), 0)
+	payloads := transport.Stats()
+	assert.Len(payloads, 1)
+	assert.Equal("prod", payloads[0].Env)
+	assert.Equal(ext.Lang, payloads[0].Lang)
+	assert.Equal(uint64(1), payloads[0].Sequence)
+	stats := statsByResource(payloads[0].Stats)
+	assert.Len(stats, 1)
+	assert.Equal(uint64(10), stats["/home"].Hits)
+}
+
+func TestTransportSendStats(t *testing.T) {
+	assert := assert.New(t)
+
+	var received statsPayload
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/v0.6/stats", r.URL.Path)
+		headers = r.Header
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(err)
+		assert.Nil(codec.NewDecoder(bytes.NewReader(body), &statsHandle).Decode(&received))
+	}))
+	defer server.Close()
+
+	transport := newHTTPTransport("localhost", "0")
+	transport.statsURL = server.URL + "/v0.6/stats"
+	c := newConcentrator()
+	span := NewSpan("name", "service", "resource", 1, 1, 0, nil)
+	span.Duration = 10
+	c.add(span)
+	payload := &statsPayload{Stats: c.flush(0, true), Lang: ext.Lang, Sequence: 1}
+
+	response, err := transport.SendStats(payload)
+	assert.Nil(err)
+	assert.Equal(200, response.StatusCode)
+	assert.Equal(msgpackContentType, headers.Get("Content-Type"))
+	assert.Equal(ext.Lang, headers.Get("Datadog-Meta-Lang"))
+	assert.Equal(*payload, received)
+}
+
+func TestStatsComputationHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = r.Header
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	transport := newHTTPTransport("localhost", "0")
+	transport.traceURL = server.URL + "/v0.3/traces"
+	tracer := NewTracerTransport(transport)
+	defer tracer.Stop()
+
+	// the header can be changed while traces are sent
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			tracer.SetStatsComputation(i%2 == 0)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		transport.SendTraces(getTestTrace(1, 1))
+	}
+	wg.Wait()
+
+	tracer.SetStatsComputation(true)
+	transport.SendTraces(getTestTrace(1, 1))
+	mu.Lock()
+	assert.Equal("yes", headers.Get(clientComputedStatsHeader))
+	mu.Unlock()
+
+	tracer.SetStatsComputation(false)
+	transport.SendTraces(getTestTrace(1, 1))
+	mu.Lock()
+	_, ok := headers[clientComputedStatsHeader]
+	mu.Unlock()
+	assert.False(ok)
+}
@@ -0,0 +1,123 @@
+package tracer
+
+import (
+	"strconv"
+)
+
+// MisuseKind is a kind of misuse of the tracer API.
+type MisuseKind int
+
+const (
+	// MisuseFinishedMutation is the modification of a finished span,
+	// which is ignored.
+	MisuseFinishedMutation MisuseKind = iota + 1
+	// MisuseDoubleFinish is the finish of a span which is already
+	// finished, which is ignored.
+	MisuseDoubleFinish
+	// MisuseFlushedParent is the creation of a child of a span whose
+	// trace has already been flushed, the child being lost.
+	MisuseFlushedParent
+	// MisuseNilParent is the creation of a child of a nil span, which
+	// makes an orphan span.
+	MisuseNilParent
+)
+
+// String returns a description of the kind of misuse.
+func (k MisuseKind) String() string {
+	switch k {
+	case MisuseFinishedMutation:
+		return "finished span modified"
+	case MisuseDoubleFinish:
+		return "span finished twice"
+	case MisuseFlushedParent:
+		return "child created after its trace was flushed"
+	case MisuseNilParent:
+		return "child created with a nil parent"
+	}
+	return "misuse " + strconv.Itoa(int(k))
+}
+
+// Misuse is a misuse of the tracer API, reported in strict mode.
+type Misuse struct {
+	Kind    MisuseKind
+	Name    string // name of the span misused or created
+	TraceID uint64
+	SpanID  uint64
+	Stack   string // where the tracer was misused
+}
+
+// Error provides a readable error message.
+func (m Misuse) Error() string {
+	return "tracer misuse: " + m.Kind.String() + " (span name: '" + m.Name + "')"
+}
+
+// PanicOnMisuse panics with the given misuse. It is meant to be used as
+// the strict mode handler in tests, see Tracer.SetStrictMode.
+func PanicOnMisuse(m Misuse) {
+	panic(m)
+}
+
+// misuseHandler wraps the strict mode handler of a tracer, since an
+// atomic.Value can't hold a nil value.
+type misuseHandler struct {
+	onMisuse func(Misuse)
+}
+
+// SetStrictMode enables the strict mode, in which the misuses of the tracer
+// API, which are silently ignored otherwise, are reported to onMisuse along
+// with their call site. onMisuse is called synchronously by the goroutine
+// misusing the tracer, so PanicOnMisuse can be used to catch the misuses in
+// tests. Recording the call sites is costly, so the strict mode should not
+// be enabled in production. A nil onMisuse disables the strict mode.
+func (t *Tracer) SetStrictMode(onMisuse func(Misuse)) {
+	t.strict.Store(misuseHandler{onMisuse})
+}
+
+// StrictModeEnabled returns true if the misuses of the tracer API are
+// reported.
+func (t *Tracer) StrictModeEnabled() bool {
+	return t.misuseHandler() != nil
+}
+
+// misuseHandler returns the strict mode handler of the tracer, or nil if
+// the strict mode is disabled.
+func (t *Tracer) misuseHandler() func(Misuse) {
+	if t == nil { // Defensive, span could be initialized with nil tracer
+		return nil
+	}
+	h, _ := t.strict.Load().(misuseHandler)
+	return h.onMisuse
+}
+
+// misused reports a misuse of the given span in strict mode. The span must
+// not be locked.
+func (s *Span) misused(kind MisuseKind) {
+	onMisuse := s.tracer.misuseHandler()
+	if onMisuse == nil {
+		return
+	}
+	s.RLock()
+	m := Misuse{
+		Kind:    kind,
+		Name:    s.Name,
+		TraceID: s.TraceID,
+		SpanID:  s.SpanID,
+	}
+	s.RUnlock()
+	m.Stack = formatCallerStack(callers())
+	onMisuse(m)
+}
+
+// lockMutable locks the span to modify it, unless it is finished, in which
+// case it returns false, leaving the span unlocked: finished spans must not
+// be modified, since they may be flushed concurrently. The modification of
+// a finished span is reported in strict mode.
+func (s *Span) lockMutable() bool {
+	s.Lock()
+	if !s.finished {
+		return true
+	}
+	s.Unlock()
+	s.misused(MisuseFinishedMutation)
+	return false
+}
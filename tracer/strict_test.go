@@ -0,0 +1,128 @@
+package tracer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/stretchr/testify/assert"
+)
+
+// getStrictTracer returns a test tracer in strict mode, along with the
+// misuses it reports.
+func getStrictTracer() (*Tracer, *[]Misuse) {
+	tracer, _ := getTestTracer()
+	misuses := &[]Misuse{}
+	tracer.SetStrictMode(func(m Misuse) { *misuses = append(*misuses, m) })
+	return tracer, misuses
+}
+
+func TestStrictModeFinishedMutation(t *testing.T) {
+	assert := assert.New(t)
+	tracer, misuses := getStrictTracer()
+	defer tracer.Stop()
+
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.SetMeta("key", "value")
+	span.Finish()
+	assert.Len(*misuses, 0)
+
+	span.SetMeta("key", "other")
+	span.SetMetric("metric", 1)
+	span.SetTag(ext.ResourceName, "/other")
+	span.SetError(errors.New("oops"))
+	span.AddEvent("event", nil)
+	span.AddLink(1, 2, nil)
+	if assert.Len(*misuses, 6) {
+		m := (*misuses)[0]
+		assert.Equal(MisuseFinishedMutation, m.Kind)
+		assert.Equal("pylons.request", m.Name)
+		assert.Equal(span.TraceID, m.TraceID)
+		assert.Equal(span.SpanID, m.SpanID)
+		assert.True(strings.HasPrefix(m.Stack, "github.com/DataDog/dd-trace-go/tracer.TestStrictModeFinishedMutation\n"), m.Stack)
+	}
+	for _, m := range *misuses {
+		assert.Equal(MisuseFinishedMutation, m.Kind)
+	}
+	assert.Equal("value", span.GetMeta("key"))
+	assert.Equal("/", span.Resource)
+}
+
+func TestStrictModeDoubleFinish(t *testing.T) {
+	assert := assert.New(t)
+	tracer, misuses := getStrictTracer()
+	defer tracer.Stop()
+
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.Finish()
+	span.Finish()
+	if assert.Len(*misuses, 1) {
+		assert.Equal(MisuseDoubleFinish, (*misuses)[0].Kind)
+	}
+}
+
+func TestStrictModeParents(t *testing.T) {
+	assert := assert.New(t)
+	tracer, misuses := getStrictTracer()
+	defer tracer.Stop()
+
+	orphan := tracer.NewChildSpan("redis.command", nil)
+	defer orphan.Finish()
+	if assert.Len(*misuses, 1) {
+		assert.Equal(MisuseNilParent, (*misuses)[0].Kind)
+		assert.Equal(orphan.SpanID, (*misuses)[0].SpanID)
+	}
+
+	// children of finished parents are fine, until the trace is flushed
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	root.Finish()
+	tracer.NewChildSpan("sql.query", root).Finish()
+	assert.Len(*misuses, 1)
+
+	child.Finish()
+	late := tracer.NewChildSpan("sql.query", root)
+	defer late.Finish()
+	if assert.Len(*misuses, 2) {
+		assert.Equal(MisuseFlushedParent, (*misuses)[1].Kind)
+		assert.Equal("sql.query", (*misuses)[1].Name)
+	}
+}
+
+func TestStrictModePanic(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetStrictMode(PanicOnMisuse)
+	assert.True(tracer.StrictModeEnabled())
+
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.Finish()
+	func() {
+		defer func() {
+			m, ok := recover().(Misuse)
+			if assert.True(ok) {
+				assert.Equal(MisuseDoubleFinish, m.Kind)
+				assert.Equal(span.SpanID, m.SpanID)
+			}
+		}()
+		span.Finish()
+	}()
+	// the span is not left locked
+	assert.Equal("pylons.request", span.Name)
+	span.RLock()
+	span.RUnlock()
+
+	tracer.SetStrictMode(nil)
+	assert.False(tracer.StrictModeEnabled())
+	span.Finish()
+}
+
+func TestMisuseError(t *testing.T) {
+	assert := assert.New(t)
+
+	m := Misuse{Kind: MisuseDoubleFinish, Name: "pylons.request"}
+	assert.Equal("tracer misuse: span finished twice (span name: 'pylons.request')", m.Error())
+	assert.Equal("misuse 42", MisuseKind(42).String())
+}
@@ -0,0 +1,85 @@
+package tracer
+
+import (
+	"time"
+)
+
+// keepReasonKey is the meta set on the local root of the traces kept when
+// the tail-based sampling is enabled, telling why they were kept.
+const keepReasonKey = "_dd.keep_reason"
+
+// The reasons for which a trace is kept by the tail-based sampling.
+const (
+	keepReasonManual  = "manual"  // kept with Span.Keep
+	keepReasonError   = "error"   // one of its spans has an error
+	keepReasonLatency = "latency" // its local root exceeded the latency threshold
+	keepReasonRate    = "rate"    // kept by the sampler when it started
+)
+
+// TailSampling configures the sampling of the traces once they are
+// complete, which comes on top of the sampling of the traces when they
+// start, see SetSampleRate. The traces which contain an error span, or whose
+// local root is slower than the latency threshold of its resource, are
+// always kept, the other traces being kept according to the sample rate.
+// A decision taken with Span.Keep or Span.Drop takes precedence.
+//
+// Traces are always held in memory until they are complete, whatever the
+// sampling, so the memory used is bounded by the limits of the span buffers.
+// The decision propagated to other services is still the one taken when the
+// trace started.
+type TailSampling struct {
+	// Enabled enables the tail-based sampling.
+	Enabled bool
+	// Latency is the duration of the local root above which a trace is
+	// kept. Zero disables the latency threshold.
+	Latency time.Duration
+	// ResourceLatency holds the latency thresholds of the traces whose
+	// local root has the given resource, overriding Latency.
+	ResourceLatency map[string]time.Duration
+}
+
+// SetTailSampling configures the sampling of the traces once they are
+// complete.
+func (t *Tracer) SetTailSampling(cfg TailSampling) {
+	t.tailSampling.Store(cfg)
+}
+
+// TailSampling returns the configuration of the sampling of the traces once
+// they are complete.
+func (t *Tracer) TailSampling() TailSampling {
+	if t == nil { // Defensive, span could be initialized with nil tracer
+		return TailSampling{}
+	}
+	cfg, _ := t.tailSampling.Load().(TailSampling)
+	return cfg
+}
+
+// latency returns the latency threshold of the traces whose local root has
+// the given resource, zero meaning there is none.
+func (cfg TailSampling) latency(resource string) time.Duration {
+	if latency, ok := cfg.ResourceLatency[resource]; ok {
+		return latency
+	}
+	return cfg.Latency
+}
+
+// keepReason returns the reason for which the given complete trace, with the
+// given local root, must be kept whatever the sample rate, or the empty
+// string if there is none.
+func (cfg TailSampling) keepReason(root *Span, spans []*Span) string {
+	for _, s := range spans {
+		s.RLock()
+		failed := s.Error != 0
+		s.RUnlock()
+		if failed {
+			return keepReasonError
+		}
+	}
+	root.RLock()
+	duration, resource := time.Duration(root.Duration), root.Resource
+	root.RUnlock()
+	if latency := cfg.latency(resource); latency > 0 && duration > latency {
+		return keepReasonLatency
+	}
+	return ""
+}
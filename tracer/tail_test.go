@@ -0,0 +1,143 @@
+package tracer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTailSampling(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	clock := &fakeClock{now: time.Unix(1500000000, 0)}
+	tracer.SetClock(clock)
+	tracer.SetSampleRate(0)
+	tracer.SetTailSampling(TailSampling{
+		Enabled:         true,
+		Latency:         time.Second,
+		ResourceLatency: map[string]time.Duration{"/export": time.Minute},
+	})
+
+	// rejected by the sampler
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+
+	// kept because of an error
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	tracer.NewChildSpan("redis.command", root).FinishWithErr(errors.New("timeout"))
+	root.Finish()
+
+	// kept because of the latency
+	slow := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	clock.Advance(2 * time.Second)
+	slow.Finish()
+
+	// below the threshold of its resource
+	export := tracer.NewRootSpan("pylons.request", "pylons", "/export")
+	clock.Advance(2 * time.Second)
+	export.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	if assert.Len(traces, 2) {
+		assert.Equal(root, traces[0][0])
+		assert.Equal(keepReasonError, root.Meta[keepReasonKey])
+		assert.Equal(float64(ext.PriorityAutoKeep), root.Metrics[samplingPriorityKey])
+		assert.Equal(slow, traces[1][0])
+		assert.Equal(keepReasonLatency, slow.Meta[keepReasonKey])
+	}
+	assert.Equal(uint64(2), tracer.Stats().TracesDropped.Sampler)
+}
+
+func TestTailSamplingReasons(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetTailSampling(TailSampling{Enabled: true})
+
+	// kept by the sampler
+	sampled := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	sampled.Finish()
+
+	// the decisions taken with Keep or Drop take precedence
+	kept := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	kept.Keep()
+	kept.Finish()
+	dropped := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	dropped.SetError(errors.New("oops"))
+	dropped.Drop()
+	dropped.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	if assert.Len(traces, 2) {
+		assert.Equal(keepReasonRate, traces[0][0].Meta[keepReasonKey])
+		assert.Equal(keepReasonManual, traces[1][0].Meta[keepReasonKey])
+	}
+}
+
+func TestTailSamplingDisabled(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	assert.Equal(TailSampling{}, tracer.TailSampling())
+	tracer.SetSampleRate(0)
+
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.SetError(errors.New("oops"))
+	span.Finish()
+	tracer.SetSampleRate(1)
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	if assert.Len(traces, 1) {
+		assert.NotContains(traces[0][0].Meta, keepReasonKey)
+	}
+}
+
+func TestTailSamplingLateSpans(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	clock := &fakeClock{now: time.Unix(1500000000, 0)}
+	tracer.SetClock(clock)
+	tracer.SetSampleRate(0)
+	tracer.SetTailSampling(TailSampling{
+		Enabled:         true,
+		ResourceLatency: map[string]time.Duration{"/slow": time.Second},
+	})
+
+	// the root is flushed alone, and slow enough for its trace to be kept
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/slow")
+	clock.Advance(2 * time.Second)
+	root.Finish()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 1)
+
+	// the spans flushed later are decided from the local root, which is
+	// not modified anymore
+	late := tracer.NewChildSpan("redis.command", root)
+	late.SetTag(ext.ResourceName, "/fast")
+	late.Finish()
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	if assert.Len(traces, 1) && assert.Len(traces[0], 1) {
+		assert.Equal(late, traces[0][0])
+		assert.Equal(keepReasonLatency, late.Meta[keepReasonKey])
+		assert.Equal(float64(ext.PriorityAutoKeep), late.Metrics[samplingPriorityKey])
+	}
+	assert.Equal(keepReasonLatency, root.Meta[keepReasonKey])
+
+	// so is a decision taken on a late span
+	late = tracer.NewChildSpan("redis.command", root)
+	late.Drop()
+	late.Finish()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+	assert.NotEqual(float64(ext.PriorityUserReject), root.Metrics[samplingPriorityKey])
+}
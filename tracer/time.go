@@ -4,7 +4,7 @@ package tracer
 
 import "time"
 
-// now returns current UTC time in nanos.
-func now() int64 {
-	return time.Now().UTC().UnixNano()
+// Now returns the current time, with a monotonic clock reading.
+func (systemClock) Now() time.Time {
+	return time.Now()
 }
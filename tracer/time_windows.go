@@ -2,7 +2,6 @@ package tracer
 
 import (
 	"golang.org/x/sys/windows"
-	"log"
 	"time"
 )
 
@@ -22,13 +21,26 @@ func lowPrecisionNow() int64 {
 var now func() int64
 
 // If GetSystemTimePreciseAsFileTime is not available we default to the less
-// precise implementation based on time.Now()
+// precise implementation based on time.Now(). The choice is logged by the
+// first flush of a tracer, see clockLog.
 func init() {
 	if err := windows.LoadGetSystemTimePreciseAsFileTime(); err != nil {
-		log.Printf("Unable to load high precison timer, defaulting to time.Now()")
+		clockLog = func(l Logger) {
+			l.Log(LevelWarn, "unable to load high precision timer, defaulting to time.Now()", "error", err)
+		}
 		now = lowPrecisionNow
 	} else {
-		log.Printf("Using high precision timer")
+		clockLog = func(l Logger) {
+			l.Log(LevelInfo, "using high precision timer")
+		}
 		now = highPrecisionNow
 	}
 }
+
+// Now returns the current time, using the high precision timer if it's
+// available.
+func (systemClock) Now() time.Time {
+	t := time.Now()
+	// adding a duration keeps the monotonic clock reading of t
+	return t.Add(time.Duration(now() - t.UnixNano()))
+}
@@ -2,8 +2,6 @@ package tracer
 
 import (
 	"context"
-	"log"
-	"math/rand"
 	"os"
 	"strconv"
 	"sync"
@@ -17,10 +15,6 @@ const (
 	flushInterval = 2 * time.Second
 )
 
-func init() {
-	randGen = rand.New(newRandSource())
-}
-
 type Service struct {
 	Name    string `json:"-"`        // the internal of the service (e.g. acme_search, datadog_web)
 	App     string `json:"app"`      // the name of the application (e.g. rails, postgres, custom-app)
@@ -43,22 +37,67 @@ type Tracer struct {
 	// a value of 1 and disabled when 0.
 	debugMode uint32
 
+	// poolSpans should only be set atomically. Spans are reused once
+	// flushed when it has a value of 1.
+	poolSpans uint32
+
+	// recoverPanics should only be set atomically. Panics recovered by Trace
+	// and Go are swallowed when it has a value of 1 and propagated when 0.
+	recoverPanics uint32
+
+	// statsEnabled should only be set atomically. Stats computation is
+	// enabled when it has a value of 1 and disabled when 0.
+	statsEnabled uint32
+	stats        *concentrator
+	statsSeq     uint64 // sequence number of the stats payloads, only used by the worker
+
 	enableMu sync.RWMutex
 	enabled  bool // defines if the Tracer is enabled or not
 
-	meta   map[string]string
-	metaMu sync.RWMutex
+	// meta holds the tags set at the tracer level, as a map[string]string
+	// that is never modified once stored: spans keep a reference to it
+	// instead of copying it, and its tags are applied when traces are
+	// flushed. metaMu serializes the writers.
+	meta   atomic.Value
+	metaMu sync.Mutex
+
+	errorConfig atomic.Value // ErrorConfig used by Span.SetError
+	idGenerator atomic.Value // IDGenerator generating the span and trace ids
+	clock       atomic.Value // Clock timing the spans
+	spanLimits  atomic.Value // SpanLimits enforced when spans are finished
+	lastFlush   atomic.Value // flushResult of the last attempt to send traces
+	apiVersion  atomic.Value // agent API version targeted by the transport
+
+	// debug holds the *debugRecorder of the debug handler, once one has
+	// been created. debugMu serializes its creation.
+	debug   atomic.Value
+	debugMu sync.Mutex
+
+	// trackOpen should only be set atomically. The traces are tracked in
+	// open until they are finished when it has a value of 1.
+	trackOpen uint32
+	open      openTraces
+
+	leakDetection atomic.Value // LeakDetection of the traces never finished
+	tailSampling  atomic.Value // TailSampling of the complete traces
+	strict        atomic.Value // misuseHandler reporting misuses in strict mode
+	logger        atomic.Value // Logger of the tracer, see SetLogger
 
 	channels tracerChans
 	services map[string]Service // name -> service
 
-	exit   chan struct{}
-	exitWG *sync.WaitGroup
+	stopOnce          sync.Once
+	droppedBeforeStop uint64        // value of droppedTransport when the shutdown started
+	exit              chan struct{} // closed to make the worker exit
+	done              chan struct{} // closed once the worker has exited
 
 	forceFlushIn  chan struct{}
 	forceFlushOut chan struct{}
 }
 
+// pid is the process id, as it is set on root spans.
+var pid = strconv.Itoa(os.Getpid())
+
 // NewTracer creates a new Tracer. Most users should use the package's
 // DefaultTracer instance.
 func NewTracer() *Tracer {
@@ -75,25 +114,72 @@ func NewTracerTransport(transport Transport) *Tracer {
 		channels: newTracerChans(),
 
 		services: make(map[string]Service),
+		stats:    newConcentrator(),
 
-		exit:   make(chan struct{}),
-		exitWG: &sync.WaitGroup{},
+		exit: make(chan struct{}),
+		done: make(chan struct{}),
 
 		forceFlushIn:  make(chan struct{}, 0), // must be size 0 (blocking)
 		forceFlushOut: make(chan struct{}, 0), // must be size 0 (blocking)
 	}
 
 	// start a background worker
-	t.exitWG.Add(1)
 	go t.worker()
 
 	return t
 }
 
-// Stop stops the tracer.
+// Stop stops the tracer, waiting for the pending traces to be flushed. It
+// is the same as calling Shutdown with no deadline.
 func (t *Tracer) Stop() {
-	close(t.exit)
-	t.exitWG.Wait()
+	t.Shutdown(context.Background())
+}
+
+// Shutdown stops the tracer gracefully: the pending traces, services and
+// stats are flushed, along with the traces finished until the final flush,
+// the ones finished later being dropped. If ctx is done before the flush
+// completes, Shutdown returns ctx.Err() without waiting any longer. It
+// returns the number of traces that have been dropped, because they could
+// not be sent, were finished too late or because ctx was done before.
+// Shutdown is idempotent and can be called concurrently.
+func (t *Tracer) Shutdown(ctx context.Context) (dropped int, err error) {
+	t.stopOnce.Do(func() {
+		t.droppedBeforeStop = atomic.LoadUint64(&t.channels.counters.droppedTransport)
+		close(t.exit)
+	})
+
+	select {
+	case <-t.done:
+	default:
+		select {
+		case <-t.done:
+		case <-ctx.Done():
+			err = ctx.Err()
+			// traces being sent are considered lost
+			dropped = int(atomic.LoadUint64(&t.channels.counters.sendingTraces))
+		}
+	}
+	// so are the ones still queued, which are drained, and the ones
+	// finished too late
+	t.channels.close()
+	counters := t.channels.counters
+	dropped += int(atomic.LoadUint64(&counters.droppedTransport)-t.droppedBeforeStop) +
+		int(atomic.LoadUint64(&counters.droppedShutdown))
+	return dropped, err
+}
+
+// OnError sets a function called with every internal error of the tracer,
+// such as traces being dropped or failing to be sent. The errors are the
+// Error* types of this package, or the errors returned by the transport.
+// The function is called synchronously by the goroutine where the error
+// happens, possibly user code, so it must be fast and safe for concurrent
+// use. It is never called with a lock of the tracer or of a trace held, so
+// it can create and finish spans, but it may be called by the worker which
+// flushes the traces, so it must not call ForceFlush, Stop or Shutdown,
+// which wait for the worker. The errors are still logged. A nil function
+// removes the hook.
+func (t *Tracer) OnError(fn func(error)) {
+	t.channels.onError.Store(errorHandler{fn})
 }
 
 // SetEnabled will enable or disable the tracer.
@@ -120,7 +206,7 @@ func (t *Tracer) SetSampleRate(sampleRate float64) {
 	} else if sampleRate >= 0 && sampleRate < 1 {
 		t.sampler = newRateSampler(sampleRate)
 	} else {
-		log.Printf("tracer.SetSampleRate rate must be between 0 and 1, now: %f", sampleRate)
+		t.Logger().Log(LevelWarn, "tracer.SetSampleRate rate must be between 0 and 1", "rate", sampleRate)
 	}
 }
 
@@ -142,55 +228,118 @@ func (t *Tracer) SetMeta(key, value string) {
 	}
 
 	t.metaMu.Lock()
-	if t.meta == nil {
-		t.meta = make(map[string]string)
+	defer t.metaMu.Unlock()
+	// copy on write, as the current map may be referenced by spans
+	old := t.globalMeta()
+	meta := make(map[string]string, len(old)+1)
+	for k, v := range old {
+		meta[k] = v
 	}
-	t.meta[key] = value
-	t.metaMu.Unlock()
+	meta[key] = value
+	t.meta.Store(meta)
 }
 
-// getAllMeta returns all the meta set by this tracer.
-// In most cases, it is nil.
-func (t *Tracer) getAllMeta() map[string]string {
+// globalMeta returns the meta set by this tracer, which must not be
+// modified. In most cases, it is nil.
+func (t *Tracer) globalMeta() map[string]string {
 	if t == nil { // Defensive, span could be initialized with nil tracer
 		return nil
 	}
+	meta, _ := t.meta.Load().(map[string]string)
+	return meta
+}
 
-	var meta map[string]string
-
-	t.metaMu.RLock()
-	if t.meta != nil {
-		meta = make(map[string]string, len(t.meta))
-		for key, value := range t.meta {
-			meta[key] = value
-		}
+// getAllMeta returns a copy of all the meta set by this tracer.
+// In most cases, it is nil.
+func (t *Tracer) getAllMeta() map[string]string {
+	global := t.globalMeta()
+	if global == nil {
+		return nil
+	}
+	meta := make(map[string]string, len(global))
+	for key, value := range global {
+		meta[key] = value
 	}
-	t.metaMu.RUnlock()
-
 	return meta
 }
 
+// getMeta returns the value of the given meta set by this tracer.
+func (t *Tracer) getMeta(key string) string {
+	return t.globalMeta()[key]
+}
+
 // NewRootSpan creates a span with no parent. Its ids will be randomly
 // assigned.
 func (t *Tracer) NewRootSpan(name, service, resource string) *Span {
-	spanID := NextSpanID()
-	span := NewSpan(name, service, resource, spanID, spanID, 0, t)
+	gen := t.IDGenerator()
+	spanID := gen.SpanID()
+	high, traceID := gen.TraceID(spanID)
+	span := NewSpan(name, service, resource, spanID, traceID, 0, t)
+	if high != 0 {
+		span.SetMeta(traceIDHighKey, formatTraceIDHigh(high))
+	}
+	t.newTrace(span)
+
+	// Add the process id to all root spans
+	span.SetMeta(ext.Pid, pid)
+
+	return span
+}
+
+// RemoteParent is the context of a span propagated from another process,
+// which is continued locally with NewRemoteChildSpan.
+type RemoteParent struct {
+	TraceID     uint64
+	SpanID      uint64
+	Priority    int  // sampling priority, if HasPriority is true
+	HasPriority bool // whether a sampling priority has been propagated
+	Origin      string
+	Baggage     map[string]string
+}
+
+// NewRemoteChildSpan creates a span that is a child of a span of another
+// process, described by parent. It is the local root of the trace, which
+// is sampled once its propagated IDs, sampling priority, origin and
+// baggage are known.
+func (t *Tracer) NewRemoteChildSpan(name, service, resource string, parent RemoteParent) *Span {
+	spanID := t.IDGenerator().SpanID()
+	span := NewSpan(name, service, resource, spanID, parent.TraceID, parent.SpanID, t)
+	if parent.HasPriority {
+		span.SetSamplingPriority(parent.Priority)
+	}
+	if parent.Origin != "" {
+		span.SetOrigin(parent.Origin)
+	}
+	if len(parent.Baggage) > 0 {
+		span.baggage = make(map[string]string, len(parent.Baggage))
+		for k, v := range parent.Baggage {
+			span.baggage[k] = v
+		}
+	}
+	t.newTrace(span)
+
+	// Add the process id to all root spans
+	span.SetMeta(ext.Pid, pid)
 
+	return span
+}
+
+// newTrace starts a new trace with the given span as its root.
+func (t *Tracer) newTrace(span *Span) {
 	span.buffer = newSpanBuffer(t.channels, 0, 0)
 	t.Sample(span)
 	// [TODO:christian] introduce distributed sampling here
 	span.buffer.Push(span)
 
-	// Add the process id to all root spans
-	span.SetMeta(ext.Pid, strconv.Itoa(os.Getpid()))
-
-	return span
+	if t.tracksOpenTraces() {
+		t.open.add(span)
+	}
 }
 
 // NewChildSpan returns a new span that is child of the Span passed as
 // argument.
 func (t *Tracer) NewChildSpan(name string, parent *Span) *Span {
-	spanID := NextSpanID()
+	spanID := t.IDGenerator().SpanID()
 
 	// when we're using parenting in inner functions, it's possible that
 	// a nil pointer is sent to this function as argument. To prevent a crash,
@@ -198,12 +347,8 @@ func (t *Tracer) NewChildSpan(name string, parent *Span) *Span {
 	// that is not sent to the trace agent.
 	if parent == nil {
 		span := NewSpan(name, "", name, spanID, spanID, spanID, t)
-
-		span.buffer = newSpanBuffer(t.channels, 0, 0)
-		t.Sample(span)
-		// [TODO:christian] introduce distributed sampling here
-		span.buffer.Push(span)
-
+		t.newTrace(span)
+		span.misused(MisuseNilParent)
 		return span
 	}
 
@@ -211,16 +356,27 @@ func (t *Tracer) NewChildSpan(name string, parent *Span) *Span {
 	// child that is correctly configured
 	span := NewSpan(name, parent.Service, name, spanID, parent.TraceID, parent.SpanID, parent.tracer)
 
-	// child sampling same as the parent
+	// child sampling same as the parent, or as decided for the whole trace
 	span.Sampled = parent.Sampled
+	if priority, ok := parent.buffer.samplingPriority(); ok {
+		span.Sampled = priority > ext.PriorityAutoReject
+	}
 	if parent.HasSamplingPriority() {
 		span.SetSamplingPriority(parent.GetSamplingPriority())
 	}
+	if origin, ok := parent.Meta[originKey]; ok {
+		span.SetOrigin(origin)
+	}
 
 	span.parent = parent
 	span.buffer = parent.buffer
+	span.baggage = parent.baggage
+	parentFinished := parent.finished
 	parent.RUnlock()
 
+	if parentFinished && t.StrictModeEnabled() && span.buffer.flushed() {
+		span.misused(MisuseFlushedParent)
+	}
 	span.buffer.Push(span)
 
 	return span
@@ -257,6 +413,117 @@ func (t *Tracer) DebugLoggingEnabled() bool {
 	return atomic.LoadUint32(&t.debugMode) == 1
 }
 
+// SetSpanPooling enables or disables the reuse of the meta and metrics maps
+// of the spans once they have been flushed, which reduces the allocations
+// made when creating spans. The flushed spans are left without meta and
+// metrics, so it is disabled by default and must only be enabled if the
+// tags of the spans are not read after they are finished. The spans can
+// still be used as parents, even once flushed.
+func (t *Tracer) SetSpanPooling(enabled bool) {
+	if enabled {
+		atomic.StoreUint32(&t.poolSpans, 1)
+	} else {
+		atomic.StoreUint32(&t.poolSpans, 0)
+	}
+}
+
+// SpanPoolingEnabled returns true if the spans are reused once flushed.
+func (t *Tracer) SpanPoolingEnabled() bool {
+	if t == nil {
+		return false
+	}
+	return atomic.LoadUint32(&t.poolSpans) == 1
+}
+
+// SetIDGenerator sets the generator of the span and trace ids, for instance
+// to generate deterministic ids in tests or 128-bit trace ids. A nil
+// generator restores the default one, which generates random 63-bit ids.
+func (t *Tracer) SetIDGenerator(gen IDGenerator) {
+	if gen == nil {
+		gen = defaultIDGenerator
+	}
+	t.idGenerator.Store(idGeneratorHolder{gen})
+}
+
+// IDGenerator returns the generator of the span and trace ids.
+func (t *Tracer) IDGenerator() IDGenerator {
+	if t == nil {
+		return defaultIDGenerator
+	}
+	if h, ok := t.idGenerator.Load().(idGeneratorHolder); ok {
+		return h.IDGenerator
+	}
+	return defaultIDGenerator
+}
+
+// idGeneratorHolder wraps the IDGenerator of a tracer, since an atomic.Value
+// must always hold values of the same concrete type.
+type idGeneratorHolder struct{ IDGenerator }
+
+// SetErrorConfig configures how errors are recorded by Span.SetError:
+// stack trace capture, depth and skipped frames.
+func (t *Tracer) SetErrorConfig(cfg ErrorConfig) {
+	t.errorConfig.Store(cfg)
+}
+
+// ErrorConfig returns the configuration used to record errors.
+func (t *Tracer) ErrorConfig() ErrorConfig {
+	if t == nil { // Defensive, span could be initialized with nil tracer
+		return ErrorConfig{}
+	}
+	cfg, _ := t.errorConfig.Load().(ErrorConfig)
+	return cfg
+}
+
+// SetSpanLimits sets the limits enforced on the size of the spans when
+// they are finished.
+func (t *Tracer) SetSpanLimits(limits SpanLimits) {
+	t.spanLimits.Store(limits)
+}
+
+// SpanLimits returns the limits enforced on the size of the spans.
+func (t *Tracer) SpanLimits() SpanLimits {
+	if t == nil { // Defensive, span could be initialized with nil tracer
+		return SpanLimits{}
+	}
+	limits, _ := t.spanLimits.Load().(SpanLimits)
+	return limits
+}
+
+// countLimits records the values truncated and the tags dropped because of
+// the span limits.
+func (t *Tracer) countLimits(truncated, dropped int) {
+	if truncated > 0 {
+		atomic.AddUint64(&t.channels.counters.truncatedValues, uint64(truncated))
+	}
+	if dropped > 0 {
+		atomic.AddUint64(&t.channels.counters.droppedTags, uint64(dropped))
+	}
+}
+
+// SetStatsComputation enables or disables the computation of trace stats by
+// the tracer. When enabled, hits, errors and latency distributions of all the
+// top-level and measured spans are computed before sampling and sent to the
+// agent, so that they are exact whatever the sample rate is. It requires an
+// agent supporting the v0.6/stats endpoint.
+func (t *Tracer) SetStatsComputation(enabled bool) {
+	value := "" // removes the header
+	if enabled {
+		atomic.StoreUint32(&t.statsEnabled, 1)
+		value = "yes"
+	} else {
+		atomic.StoreUint32(&t.statsEnabled, 0)
+	}
+	if t.transport != nil {
+		t.transport.SetHeader(clientComputedStatsHeader, value)
+	}
+}
+
+// StatsComputationEnabled returns true if the tracer computes trace stats.
+func (t *Tracer) StatsComputationEnabled() bool {
+	return atomic.LoadUint32(&t.statsEnabled) == 1
+}
+
 func (t *Tracer) getTraces() [][]*Span {
 	traces := make([][]*Span, 0, len(t.channels.trace))
 
@@ -273,14 +540,20 @@ func (t *Tracer) getTraces() [][]*Span {
 // flushTraces will push any currently buffered traces to the server.
 func (t *Tracer) flushTraces() {
 	traces := t.getTraces()
+	for _, trace := range traces {
+		for _, span := range trace {
+			span.applyGlobalMeta()
+		}
+	}
 
 	if t.DebugLoggingEnabled() {
-		log.Printf("Sending %d traces", len(traces))
+		logger := t.Logger()
+		logger.Log(LevelDebug, "sending traces", "count", len(traces))
 		for _, trace := range traces {
 			if len(trace) > 0 {
-				log.Printf("TRACE: %d\n", trace[0].TraceID)
+				logger.Log(LevelDebug, "trace", "trace_id", trace[0].TraceID)
 				for _, span := range trace {
-					log.Printf("SPAN:\n%s", span.String())
+					logger.Log(LevelDebug, "span", "span", "\n"+span.String())
 				}
 			}
 		}
@@ -291,10 +564,29 @@ func (t *Tracer) flushTraces() {
 		return
 	}
 
+	counters := t.channels.counters
+	atomic.StoreUint64(&counters.sendingTraces, uint64(len(traces)))
 	_, err := t.transport.SendTraces(traces)
 	if err != nil {
+		atomic.AddUint64(&counters.droppedTransport, uint64(len(traces)))
+		t.recordTransportError(err)
 		t.channels.pushErr(err)
-		t.channels.pushErr(&errorFlushLostTraces{Nb: len(traces)}) // explicit log messages with nb of lost traces
+		t.channels.pushErr(&ErrorFlushLostTraces{Nb: len(traces)}) // explicit log messages with nb of lost traces
+	} else {
+		atomic.AddUint64(&counters.tracesFlushed, uint64(len(traces)))
+	}
+	atomic.StoreUint64(&counters.sendingTraces, 0)
+	t.recordFlush(err)
+	if rec := t.debugRecorder(); rec != nil {
+		rec.flushTraces(t.Clock().Now(), traces, err)
+	}
+
+	if t.SpanPoolingEnabled() {
+		for _, trace := range traces {
+			for _, span := range trace {
+				releaseSpan(span)
+			}
+		}
 	}
 }
 
@@ -323,19 +615,58 @@ func (t *Tracer) flushServices() {
 
 	_, err := t.transport.SendServices(t.services)
 	if err != nil {
+		t.recordTransportError(err)
 		t.channels.pushErr(err)
-		t.channels.pushErr(&errorFlushLostServices{Nb: len(t.services)}) // explicit log messages with nb of lost services
+		t.channels.pushErr(&ErrorFlushLostServices{Nb: len(t.services)}) // explicit log messages with nb of lost services
+	}
+}
+
+// flushStats sends the computed stats to the agent. Only the complete
+// buckets are sent, unless force is true.
+func (t *Tracer) flushStats(force bool) {
+	buckets := t.stats.flush(t.Clock().Now().UnixNano(), force)
+
+	if !t.Enabled() || len(buckets) == 0 {
+		return
+	}
+	sender, ok := t.transport.(statsSender)
+	if !ok {
+		// the transport doesn't know how to send stats
+		return
+	}
+
+	t.statsSeq++
+	payload := &statsPayload{
+		Env:           t.getMeta(ext.Environment),
+		Version:       t.getMeta(ext.Version),
+		Stats:         buckets,
+		Lang:          ext.Lang,
+		TracerVersion: ext.TracerVersion,
+		Sequence:      t.statsSeq,
+	}
+	_, err := sender.SendStats(payload)
+	if err != nil {
+		t.recordTransportError(err)
+		t.channels.pushErr(err)
+		t.channels.pushErr(&ErrorFlushLostStats{Nb: len(buckets)}) // explicit log messages with nb of lost buckets
 	}
 }
 
 // flushErrs will process log messages that were queued
 func (t *Tracer) flushErrs() {
-	logErrors(t.channels.err)
+	logErrors(t.Logger(), t.channels.err)
 }
 
 func (t *Tracer) flush() {
+	clockLogOnce.Do(func() {
+		if clockLog != nil {
+			clockLog(t.Logger())
+		}
+	})
+	t.detectLeaks()
 	t.flushTraces()
 	t.flushServices()
+	t.flushStats(false)
 	t.flushErrs()
 }
 
@@ -343,18 +674,34 @@ func (t *Tracer) flush() {
 // Flushes are done by a background task on a regular basis, so you never
 // need to call this manually, mostly useful for testing and debugging.
 func (t *Tracer) ForceFlush() {
-	t.forceFlushIn <- struct{}{}
-	<-t.forceFlushOut
+	select {
+	case t.forceFlushIn <- struct{}{}:
+		<-t.forceFlushOut
+	case <-t.done:
+		// the worker has exited, there's nothing to flush anymore
+	}
 }
 
-// Sample samples a span with the internal sampler.
+// Sample samples a span with the internal sampler. If the span already
+// has a sampling priority, typically because it was propagated from another
+// process, the decision is inherited from it and the sampler is not used.
 func (t *Tracer) Sample(span *Span) {
-	t.sampler.Sample(span)
+	span.RLock()
+	hasPriority, priority := span.HasSamplingPriority(), span.GetSamplingPriority()
+	span.RUnlock()
+	if hasPriority {
+		span.Sampled = priority > ext.PriorityAutoReject
+	} else {
+		t.sampler.Sample(span)
+	}
+	if rec := t.debugRecorder(); rec != nil {
+		rec.sample(t.Clock().Now(), span, hasPriority)
+	}
 }
 
 // worker periodically flushes traces and services to the transport.
 func (t *Tracer) worker() {
-	defer t.exitWG.Done()
+	defer close(t.done)
 
 	flushTicker := time.NewTicker(flushInterval)
 	defer flushTicker.Stop()
@@ -365,6 +712,7 @@ func (t *Tracer) worker() {
 			t.flush()
 
 		case <-t.forceFlushIn:
+			t.flushStats(true)
 			t.flush()
 			t.forceFlushOut <- struct{}{} // caller blocked until this is done
 
@@ -378,7 +726,9 @@ func (t *Tracer) worker() {
 			t.flushErrs()
 
 		case <-t.exit:
+			t.flushStats(true)
 			t.flush()
+			t.channels.close()
 			return
 		}
 	}
@@ -398,6 +748,12 @@ func NewRootSpan(name, service, resource string) *Span {
 	return DefaultTracer.NewRootSpan(name, service, resource)
 }
 
+// NewRemoteChildSpan creates a span that is a child of a span of another
+// process, described by parent.
+func NewRemoteChildSpan(name, service, resource string, parent RemoteParent) *Span {
+	return DefaultTracer.NewRemoteChildSpan(name, service, resource, parent)
+}
+
 // NewChildSpan creates a span that is a child of parent. It will inherit the
 // parent's service and resource.
 func NewChildSpan(name string, parent *Span) *Span {
@@ -155,6 +155,46 @@ func TestNewSpanChild(t *testing.T) {
 	assert.Equal(tracer, child.tracer)
 }
 
+func TestNewRemoteChildSpan(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	h := tracer.DebugHandler()
+
+	span := tracer.NewRemoteChildSpan("pylons.request", "pylons", "/", RemoteParent{
+		TraceID:     42,
+		SpanID:      43,
+		Priority:    ext.PriorityUserKeep,
+		HasPriority: true,
+		Origin:      "synthetics",
+		Baggage:     map[string]string{"user": "alice"},
+	})
+	// ids, sampling priority, origin and baggage are propagated
+	assert.Equal(uint64(42), span.TraceID)
+	assert.Equal(uint64(43), span.ParentID)
+	assert.NotEqual(uint64(43), span.SpanID)
+	assert.True(span.Sampled)
+	assert.Equal(ext.PriorityUserKeep, span.GetSamplingPriority())
+	assert.Equal("synthetics", span.GetOrigin())
+	assert.Equal("alice", span.BaggageItem("user"))
+	assert.Equal(strconv.Itoa(os.Getpid()), span.GetMeta(ext.Pid))
+
+	// the trace is sampled once, from the propagated sampling priority
+	report := getDebugReport(t, h)
+	if assert.Len(report.Decisions, 1) {
+		decision := report.Decisions[0]
+		assert.Equal(uint64(42), decision.TraceID)
+		assert.True(decision.Sampled)
+		if assert.NotNil(decision.Priority) {
+			assert.Equal(ext.PriorityUserKeep, *decision.Priority)
+		}
+	}
+	if assert.Len(report.Open, 1) {
+		assert.Equal(uint64(42), report.Open[0].TraceID)
+	}
+	span.Finish()
+}
+
 func TestNewRootSpanHasPid(t *testing.T) {
 	assert := assert.New(t)
 
@@ -239,6 +279,104 @@ func TestTracerEdgeSampler(t *testing.T) {
 	tracer1.Stop()
 }
 
+func TestTracerSamplerPriority(t *testing.T) {
+	assert := assert.New(t)
+
+	// a propagated sampling priority overrides the sampler decision
+	tracer := NewTracer()
+	tracer.SetSampleRate(0)
+	defer tracer.Stop()
+
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.False(span.Sampled)
+	span.SetSamplingPriority(ext.PriorityAutoKeep)
+	tracer.Sample(span)
+	assert.True(span.Sampled)
+	child := tracer.NewChildSpan("child", span)
+	assert.True(child.Sampled)
+
+	tracer.SetSampleRate(1)
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.True(span.Sampled)
+	span.SetSamplingPriority(ext.PriorityUserReject)
+	tracer.Sample(span)
+	assert.False(span.Sampled)
+}
+
+func TestSpanKeep(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0)
+
+	// keeping any span keeps the whole trace, rejected by the sampler
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	assert.False(child.Sampled)
+	assert.False(child.HasSamplingPriority())
+	grandchild := tracer.NewChildSpan("redis.pipeline", child)
+	grandchild.Keep()
+	assert.True(grandchild.Sampled)
+	assert.True(root.HasSamplingPriority())
+	assert.Equal(ext.PriorityUserKeep, root.GetSamplingPriority())
+	assert.Equal(ext.PriorityUserKeep, child.GetSamplingPriority())
+
+	// and applies to the spans created later
+	late := tracer.NewChildSpan("sql.query", child)
+	assert.True(late.Sampled)
+	assert.Equal(float64(ext.PriorityUserKeep), late.Metrics[samplingPriorityKey])
+
+	late.Finish()
+	grandchild.Finish()
+	child.Finish()
+	root.Finish()
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	if assert.Len(traces, 1) && assert.Len(traces[0], 4) {
+		assert.Equal(root, traces[0][0])
+		assert.True(root.Sampled)
+		assert.Equal(float64(ext.PriorityUserKeep), root.Metrics[samplingPriorityKey])
+	}
+	assert.Equal(uint64(0), tracer.Stats().TracesDropped.Sampler)
+}
+
+func TestSpanDrop(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	// the decision can be changed after the root finished, until the
+	// trace is flushed
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	root.Finish()
+	child.Drop()
+	assert.False(child.Sampled)
+	assert.True(root.Sampled)
+	assert.Equal(ext.PriorityUserReject, root.GetSamplingPriority())
+	child.Finish()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+	assert.False(root.Sampled)
+	assert.Equal(uint64(1), tracer.Stats().TracesDropped.Sampler)
+
+	// the last decision wins
+	root = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	root.Drop()
+	root.Keep()
+	root.Finish()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 1)
+
+	// without a trace, only the span is changed
+	span := NewSpan("pylons.request", "pylons", "/", 1, 1, 0, nil)
+	span.Drop()
+	assert.False(span.Sampled)
+	assert.Equal(ext.PriorityUserReject, span.GetSamplingPriority())
+	var nilSpan *Span
+	nilSpan.Keep()
+}
+
 func TestTracerConcurrent(t *testing.T) {
 	assert := assert.New(t)
 	tracer, transport := getTestTracer()
@@ -430,6 +568,102 @@ func TestTracerMeta(t *testing.T) {
 	assert.Equal(map[string]string{"env": "prod", "component": "core"}, tracer.getAllMeta(), "key1 should have been updated")
 }
 
+func TestTracerMetaFlush(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	tracer.SetMeta("env", "staging")
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.SetMeta("component", "web")
+	tracer.SetMeta("env", "prod") // doesn't change existing spans
+	tracer.SetMeta("component", "core")
+	// tracer meta is not copied into the spans when they are created
+	_, ok := span.Meta["env"]
+	assert.False(ok)
+	assert.Equal("staging", span.GetMeta("env"))
+	span.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal("staging", traces[0][0].Meta["env"])
+	assert.Equal("web", traces[0][0].Meta["component"])
+}
+
+func TestTracerSpanPooling(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	assert.False(tracer.SpanPoolingEnabled())
+	tracer.SetSpanPooling(true)
+	assert.True(tracer.SpanPoolingEnabled())
+
+	parent := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	parent.SetMeta("key", "value")
+	parent.SetMetric("metric", 1)
+	parent.Finish()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 1)
+
+	// flushed spans are left without tags, their maps being reused
+	parent.RLock()
+	assert.Nil(parent.Meta)
+	assert.Nil(parent.Metrics)
+	parent.RUnlock()
+	span := tracer.NewRootSpan("flask.request", "flask", "/home")
+	assert.Equal("flask.request", span.Name)
+	assert.Equal("", span.GetMeta("key"))
+	assert.Equal(pid, span.GetMeta(ext.Pid))
+	_, ok := span.Metrics["metric"]
+	assert.False(ok)
+
+	// but they can still be used as parents
+	child := tracer.NewChildSpan("redis.command", parent)
+	assert.Equal(parent.TraceID, child.TraceID)
+	assert.Equal(parent, child.localRoot())
+	assert.False(child.topLevel())
+
+	tracer.SetSpanPooling(false)
+	assert.False(tracer.SpanPoolingEnabled())
+}
+
+func TestTracerSpanPoolingRace(t *testing.T) {
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSpanPooling(true)
+
+	parent := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	parent.SetOrigin("synthetics")
+	parent.Finish()
+	tracer.ForceFlush()
+
+	// children of a flushed parent are created while other spans reuse
+	// the maps of the flushed ones
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			child := tracer.NewChildSpan("redis.command", parent)
+			child.SetMeta("key", "value")
+			child.Finish()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			span := tracer.NewRootSpan("flask.request", "flask", "/home")
+			span.SetMeta("key", "value")
+			span.Finish()
+			if i%10 == 0 {
+				tracer.ForceFlush()
+			}
+		}
+	}()
+	wg.Wait()
+}
+
 func TestTracerRace(t *testing.T) {
 	assert := assert.New(t)
 
@@ -602,6 +836,48 @@ func BenchmarkTracerAddSpans(b *testing.B) {
 	}
 }
 
+// BenchmarkNewSpans tests the allocations made when creating a root span
+// and a child span with tracer level meta.
+func BenchmarkNewSpans(b *testing.B) {
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetMeta("env", "prod")
+	tracer.SetMeta("version", "1.2.3")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		parent := tracer.NewRootSpan("pylons.request", "pylons", "/")
+		tracer.NewChildSpan("redis.command", parent).Finish()
+		parent.Finish()
+		if n%500 == 0 {
+			// keep the trace channel from filling up and dropping traces
+			tracer.ForceFlush()
+		}
+	}
+}
+
+// BenchmarkNewSpansPooling is like BenchmarkNewSpans but reuses the maps of
+// the spans once flushed.
+func BenchmarkNewSpansPooling(b *testing.B) {
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetMeta("env", "prod")
+	tracer.SetMeta("version", "1.2.3")
+	tracer.SetSpanPooling(true)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		parent := tracer.NewRootSpan("pylons.request", "pylons", "/")
+		tracer.NewChildSpan("redis.command", parent).Finish()
+		parent.Finish()
+		if n%500 == 0 {
+			tracer.ForceFlush()
+		}
+	}
+}
+
 // getTestTracer returns a Tracer with a DummyTransport
 func getTestTracer() (*Tracer, *dummyTransport) {
 	transport := &dummyTransport{getEncoder: msgpackEncoderFactory}
@@ -614,6 +890,7 @@ type dummyTransport struct {
 	getEncoder encoderFactory
 	traces     [][]*Span
 	services   map[string]Service
+	stats      []*statsPayload
 
 	sync.RWMutex // required because of some poll-testing (eg: worker)
 }
@@ -636,6 +913,22 @@ func (t *dummyTransport) SendServices(services map[string]Service) (*http.Respon
 	return nil, encoder.EncodeServices(services)
 }
 
+func (t *dummyTransport) SendStats(payload *statsPayload) (*http.Response, error) {
+	t.Lock()
+	t.stats = append(t.stats, payload)
+	t.Unlock()
+	return nil, nil
+}
+
+func (t *dummyTransport) Stats() []*statsPayload {
+	t.Lock()
+	defer t.Unlock()
+
+	stats := t.stats
+	t.stats = nil
+	return stats
+}
+
 func (t *dummyTransport) Traces() [][]*Span {
 	t.Lock()
 	defer t.Unlock()
@@ -1,15 +1,18 @@
 package tracer
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/ugorji/go/codec"
 )
 
 const (
@@ -23,9 +26,17 @@ const (
 type Transport interface {
 	SendTraces(spans [][]*Span) (*http.Response, error)
 	SendServices(services map[string]Service) (*http.Response, error)
+	// SetHeader sets a header of the requests sent to the agent, an empty
+	// value removing it. It can be called concurrently with the sends.
 	SetHeader(key, value string)
 }
 
+// statsSender is implemented by the transports which are able to send
+// the trace stats computed by the tracer.
+type statsSender interface {
+	SendStats(payload *statsPayload) (*http.Response, error)
+}
+
 // NewTransport returns a new Transport implementation that sends traces to a
 // trace agent running on the given hostname and port. If the zero values for
 // hostname and port are provided, the default values will be used ("localhost"
@@ -53,9 +64,12 @@ type httpTransport struct {
 	legacyTraceURL    string            // the legacy delivery URL for traces
 	serviceURL        string            // the delivery URL for services
 	legacyServiceURL  string            // the legacy delivery URL for services
+	statsURL          string            // the delivery URL for trace stats
 	client            *http.Client      // the HTTP client used in the POST
 	headers           map[string]string // the Transport headers
+	headersMu         sync.RWMutex      // guards headers, which are set concurrently with the sends
 	compatibilityMode bool              // the Agent targets a legacy API for compatibility reasons
+	log               atomic.Value      // the Logger of the tracer, see setLogger
 
 	// [WARNING] We tried to reuse encoders thanks to a pool, but that led us to having race conditions.
 	// Indeed, when we send the encoder as the request body, the persistConn.writeLoop() goroutine
@@ -81,6 +95,7 @@ func newHTTPTransport(hostname, port string) *httpTransport {
 		legacyTraceURL:   fmt.Sprintf("http://%s:%s/v0.2/traces", hostname, port),
 		serviceURL:       fmt.Sprintf("http://%s:%s/v0.3/services", hostname, port),
 		legacyServiceURL: fmt.Sprintf("http://%s:%s/v0.2/services", hostname, port),
+		statsURL:         fmt.Sprintf("http://%s:%s/v0.6/stats", hostname, port),
 		getEncoder:       msgpackEncoderFactory,
 		client: &http.Client{
 			// We copy the transport to avoid using the default one, as it might be
@@ -120,9 +135,7 @@ func (t *httpTransport) SendTraces(traces [][]*Span) (*http.Response, error) {
 
 	// prepare the client and send the payload
 	req, _ := http.NewRequest("POST", t.traceURL, encoder)
-	for header, value := range t.headers {
-		req.Header.Set(header, value)
-	}
+	t.setHeaders(req)
 	req.Header.Set(traceCountHeader, strconv.Itoa(len(traces)))
 	req.Header.Set("Content-Type", encoder.ContentType())
 	response, err := t.client.Do(req)
@@ -135,7 +148,7 @@ func (t *httpTransport) SendTraces(traces [][]*Span) (*http.Response, error) {
 
 	// if we got a 404 we should downgrade the API to a stable version (at most once)
 	if (response.StatusCode == 404 || response.StatusCode == 415) && !t.compatibilityMode {
-		log.Printf("calling the endpoint '%s' but received %d; downgrading the API\n", t.traceURL, response.StatusCode)
+		t.logger().Log(LevelWarn, "endpoint not supported by the agent, downgrading the API", "url", t.traceURL, "status", response.StatusCode)
 		t.apiDowngrade()
 		return t.SendTraces(traces)
 	}
@@ -163,9 +176,7 @@ func (t *httpTransport) SendServices(services map[string]Service) (*http.Respons
 	if err != nil {
 		return nil, fmt.Errorf("cannot create http request: %v", err)
 	}
-	for header, value := range t.headers {
-		req.Header.Set(header, value)
-	}
+	t.setHeaders(req)
 	req.Header.Set("Content-Type", encoder.ContentType())
 
 	response, err := t.client.Do(req)
@@ -176,7 +187,7 @@ func (t *httpTransport) SendServices(services map[string]Service) (*http.Respons
 
 	// Downgrade if necessary
 	if (response.StatusCode == 404 || response.StatusCode == 415) && !t.compatibilityMode {
-		log.Printf("calling the endpoint '%s' but received %d; downgrading the API\n", t.traceURL, response.StatusCode)
+		t.logger().Log(LevelWarn, "endpoint not supported by the agent, downgrading the API", "url", t.serviceURL, "status", response.StatusCode)
 		t.apiDowngrade()
 		return t.SendServices(services)
 	}
@@ -188,11 +199,83 @@ func (t *httpTransport) SendServices(services map[string]Service) (*http.Respons
 	return response, err
 }
 
-// SetHeader sets the internal header for the httpTransport
+// SendStats sends the trace stats computed by the tracer to the agent.
+// There is no downgrade for this endpoint: stats computation must only be
+// enabled with an agent supporting it.
+func (t *httpTransport) SendStats(payload *statsPayload) (*http.Response, error) {
+	if t.statsURL == "" {
+		return nil, errors.New("provided an empty URL, giving up")
+	}
+
+	var buf bytes.Buffer
+	if err := codec.NewEncoder(&buf, &statsHandle).Encode(payload); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", t.statsURL, &buf)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create http request: %v", err)
+	}
+	t.setHeaders(req)
+	req.Header.Set("Content-Type", msgpackContentType)
+
+	response, err := t.client.Do(req)
+	if err != nil {
+		return &http.Response{StatusCode: 0}, err
+	}
+	defer response.Body.Close()
+
+	if sc := response.StatusCode; sc != 200 {
+		return response, fmt.Errorf("SendStats expected response code 200, received %v", sc)
+	}
+
+	return response, err
+}
+
+// SetHeader sets the internal header for the httpTransport, an empty value
+// removing it.
 func (t *httpTransport) SetHeader(key, value string) {
+	t.headersMu.Lock()
+	defer t.headersMu.Unlock()
+	if value == "" {
+		delete(t.headers, key)
+		return
+	}
 	t.headers[key] = value
 }
 
+// setHeaders sets the headers of the transport on the given request.
+func (t *httpTransport) setHeaders(req *http.Request) {
+	t.headersMu.RLock()
+	defer t.headersMu.RUnlock()
+	for header, value := range t.headers {
+		req.Header.Set(header, value)
+	}
+}
+
+// APIVersion returns the version of the agent API targeted by the
+// transport, which changes if the API is downgraded.
+func (t *httpTransport) APIVersion() string {
+	if t.compatibilityMode {
+		return "v0.2"
+	}
+	return "v0.3"
+}
+
+// setLogger sets the logger of the transport, the default one being used
+// if it's nil.
+func (t *httpTransport) setLogger(l Logger) {
+	t.log.Store(loggerHolder{l})
+}
+
+// logger returns the logger of the transport.
+func (t *httpTransport) logger() Logger {
+	if h, ok := t.log.Load().(loggerHolder); ok && h.Logger != nil {
+		return h.Logger
+	}
+	return packageLogger()
+}
+
 // changeEncoder switches the encoder so that a different API with different
 // format can be targeted, preventing failures because of outdated agents
 func (t *httpTransport) changeEncoder(encoderFactory encoderFactory) {
@@ -0,0 +1,73 @@
+package tracer
+
+import (
+	"context"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+// UserOption sets an optional property of the user identified with SetUser.
+type UserOption func(*userConfig)
+
+type userConfig struct {
+	email, name, role, sessionID string
+	propagate                    bool
+}
+
+// WithUserEmail sets the email of the user.
+func WithUserEmail(email string) UserOption {
+	return func(cfg *userConfig) { cfg.email = email }
+}
+
+// WithUserName sets the name of the user.
+func WithUserName(name string) UserOption {
+	return func(cfg *userConfig) { cfg.name = name }
+}
+
+// WithUserRole sets the role of the user.
+func WithUserRole(role string) UserOption {
+	return func(cfg *userConfig) { cfg.role = role }
+}
+
+// WithUserSessionID sets the id of the session of the user.
+func WithUserSessionID(sessionID string) UserOption {
+	return func(cfg *userConfig) { cfg.sessionID = sessionID }
+}
+
+// WithUserPropagation makes the user id propagated to the children of the
+// span, and to other processes, as the ext.UserID baggage item.
+func WithUserPropagation() UserOption {
+	return func(cfg *userConfig) { cfg.propagate = true }
+}
+
+// SetUser identifies the user of the trace of the span contained in ctx,
+// setting the standard user tags of the ext package on its local root
+// span. It does nothing if ctx contains no span.
+func SetUser(ctx context.Context, id string, opts ...UserOption) {
+	span, ok := SpanFromContext(ctx)
+	if !ok {
+		return
+	}
+	var cfg userConfig
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+
+	root := span.localRoot()
+	root.SetMeta(ext.UserID, id)
+	if cfg.email != "" {
+		root.SetMeta(ext.UserEmail, cfg.email)
+	}
+	if cfg.name != "" {
+		root.SetMeta(ext.UserName, cfg.name)
+	}
+	if cfg.role != "" {
+		root.SetMeta(ext.UserRole, cfg.role)
+	}
+	if cfg.sessionID != "" {
+		root.SetMeta(ext.UserSessionID, cfg.sessionID)
+	}
+	if cfg.propagate {
+		span.SetBaggageItem(ext.UserID, id)
+	}
+}
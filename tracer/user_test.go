@@ -0,0 +1,98 @@
+package tracer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanSetTraceTag(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	grandchild := tracer.NewChildSpan("redis.pipeline", child)
+
+	grandchild.SetTraceTag("customer.id", "acme")
+	grandchild.SetTraceTag("customer.seats", 12)
+	root.SetTraceTag("customer.plan", "pro")
+	assert.Equal("acme", root.GetMeta("customer.id"))
+	assert.Equal(12.0, root.Metrics["customer.seats"])
+	assert.Equal("pro", root.GetMeta("customer.plan"))
+	assert.Equal("", child.GetMeta("customer.id"))
+	assert.Equal("", grandchild.GetMeta("customer.id"))
+
+	var span *Span
+	span.SetTraceTag("customer.id", "acme")
+}
+
+func TestSetUser(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child, ctx := tracer.NewChildSpanWithContext("redis.command", root.Context(context.Background()))
+	SetUser(ctx, "42", WithUserEmail("jane@example.com"), WithUserName("Jane"), WithUserRole("admin"), WithUserSessionID("s1"))
+
+	assert.Equal("42", root.GetMeta(ext.UserID))
+	assert.Equal("jane@example.com", root.GetMeta(ext.UserEmail))
+	assert.Equal("Jane", root.GetMeta(ext.UserName))
+	assert.Equal("admin", root.GetMeta(ext.UserRole))
+	assert.Equal("s1", root.GetMeta(ext.UserSessionID))
+	assert.Equal("", child.GetMeta(ext.UserID))
+	assert.Equal("", child.BaggageItem(ext.UserID))
+
+	// no span, no-op
+	SetUser(context.Background(), "42")
+}
+
+func TestSetUserPropagation(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	SetUser(root.Context(context.Background()), "42", WithUserPropagation())
+	child := tracer.NewChildSpan("redis.command", root)
+
+	assert.Equal("42", root.GetMeta(ext.UserID))
+	assert.Equal("", root.GetMeta(ext.UserEmail))
+	assert.Equal("42", root.BaggageItem(ext.UserID))
+	assert.Equal("42", child.BaggageItem(ext.UserID))
+}
+
+func TestSpanBaggage(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	root.SetBaggageItem("a", "1")
+	child := tracer.NewChildSpan("redis.command", root)
+	child.SetBaggageItem("b", "2")
+	root.SetBaggageItem("c", "3")
+
+	assert.Equal("1", child.BaggageItem("a"))
+	assert.Equal("2", child.BaggageItem("b"))
+	assert.Equal("", child.BaggageItem("c"))
+	assert.Equal("", root.BaggageItem("b"))
+
+	items := map[string]string{}
+	child.ForeachBaggageItem(func(k, v string) bool {
+		items[k] = v
+		return true
+	})
+	assert.Equal(map[string]string{"a": "1", "b": "2"}, items)
+
+	count := 0
+	child.ForeachBaggageItem(func(k, v string) bool {
+		count++
+		return false
+	})
+	assert.Equal(1, count)
+}